@@ -0,0 +1,186 @@
+// Command gofish runs a ><> script with the same flags as the official Python interpreter, fish.py, so it
+// can stand in for it in existing scripts and tooling. The go-fish command offers many more features.
+//
+// "gofish debug" runs the script under an interactive debugger instead, reading commands from stdin; type
+// "help" at its prompt for a list. As stdin holds the commands, the fish is given no input. With -x, it
+// executes the commands in a file instead, writing the results to stdout, and exits. With -headless, it
+// speaks the JSON line protocol of fish.Debugger.ServeJSON on stdin and stdout, for editors and other tools.
+//
+// "gofish repl" runs each line read from stdin as a one-line codebox, passing the stack from one line to
+// the next, for trying out instructions without writing a file.
+//
+// gofish exits with the same codes as go-fish, and like it, takes -m from GOFISH_M or gofish.toml if it
+// isn't given. Its other flags mean something else to go-fish, so their settings there are ignored.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redstarcoder/go-fish/fish"
+	"github.com/redstarcoder/go-fish/internal/cli"
+)
+
+var (
+	code     = flag.String("c", "", "execute the script supplied in 'c' instead of a file")
+	numbers  = flag.String("s", "", "push the comma separated numbers in 's' onto the initial stack (ex: 1,2,3)")
+	text     = flag.String("v", "", "push each character of 'v' onto the initial stack, after any numbers given by -s")
+	compmode = flag.Bool("m", false, "follow the behaviour of fishlanguage.com where interpreters differ")
+	tick     = flag.Float64("t", 0, "seconds to sleep between ticks, for watching the fish swim (ex: 0.1)")
+	commands = flag.String("x", "", "with debug, execute the debugger commands in the file 'x', writing the results to stdout")
+	headless = flag.Bool("headless", false, "with debug, read JSON requests from stdin and write JSON replies to stdout, instead of prompting")
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:", os.Args[0], "[debug [-x <commands> | -headless]] [args] (<file> | -c <code>)")
+	fmt.Fprintln(os.Stderr, "   or:", os.Args[0], "repl [args]")
+	flag.PrintDefaults()
+}
+
+// shared are the flags which mean the same to gofish as to go-fish, and so are taken from its environment
+// variables and configuration file.
+var shared = map[string]bool{"m": true}
+
+// configure fills in each flag in shared which wasn't given on the command line from its environment
+// variable, or failing that from gofish.toml in the working directory.
+func configure() error {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	settings, err := cli.ReadConfig(cli.ConfigName)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for name := range shared {
+		if given[name] {
+			continue
+		}
+		if v, ok := os.LookupEnv(cli.EnvName(name)); ok {
+			if err := flag.Set(name, v); err != nil {
+				return fmt.Errorf("%s: %v", cli.EnvName(name), err)
+			}
+			continue
+		}
+		for _, s := range settings {
+			if s.Name == name {
+				if err := flag.Set(name, s.Value); err != nil {
+					return fmt.Errorf("%s:%d: %s: %v", cli.ConfigName, s.Line, name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// initialStack returns the stack given by -s and -v.
+func initialStack() ([]float64, error) {
+	var s []float64
+	if *numbers != "" {
+		for _, n := range strings.Split(*numbers, ",") {
+			f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid initial stack: %q isn't a number", n)
+			}
+			s = append(s, f)
+		}
+	}
+	for _, r := range *text {
+		s = append(s, float64(r))
+	}
+	return s, nil
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	command := flag.Arg(0)
+	if command == "debug" || command == "repl" {
+		flag.CommandLine.Parse(flag.Args()[1:])
+	}
+	debugging := command == "debug"
+	if err := configure(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(cli.ExitUsage)
+	}
+	stack, err := initialStack()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(cli.ExitUsage)
+	}
+	mode := fish.Spec
+	if *compmode {
+		mode = fish.FishLanguageCom
+	}
+	if command == "repl" {
+		if err := fish.NewREPL(stack, mode).Serve(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(cli.ExitError)
+		}
+		return
+	}
+
+	script := *code
+	if script == "" {
+		if flag.NArg() != 1 {
+			usage()
+			os.Exit(cli.ExitUsage)
+		}
+		b, err := ioutil.ReadFile(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(cli.ExitUsage)
+		}
+		script = string(b)
+	}
+	p, err := fish.Compile(script, mode)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(cli.ExitInvalid)
+	}
+
+	if debugging {
+		cB := p.New(stack, fish.WithInput(strings.NewReader("")))
+		defer cB.Close()
+		d := fish.NewDebugger(cB)
+		if *commands != "" {
+			file, err := os.Open(*commands)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(cli.ExitUsage)
+			}
+			defer file.Close()
+			err = d.Script(file, os.Stdout)
+		} else if *headless {
+			err = d.ServeJSON(os.Stdin, os.Stdout)
+		} else {
+			err = d.Serve(os.Stdin)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return
+	}
+	cB := p.New(stack)
+	defer cB.Close()
+	delay := time.Duration(*tick * float64(time.Second))
+	for {
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		done, err := cB.Swim()
+		if err != nil {
+			cB.PrintFailure(err.(*fish.Failure))
+			cB.Close()
+			os.Exit(cli.ExitError)
+		}
+		if done {
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/redstarcoder/go-fish/internal/cli"
+	"os"
+)
+
+// commandLineOnly are the flags which can't be set by the environment or a configuration file, because
+// they run code: a gofish.toml in a cloned repository mustn't be able to load a plugin.
+var commandLineOnly = map[string]bool{"plugin": true, "allow": true}
+
+// configurable are the only flags a configuration file may set: limits, the dialect and instructions, and
+// how the fish is shown. A gofish.toml is read from the working directory without being asked for, so it
+// mustn't be able to choose which script runs, what it reads, or where files are written.
+var configurable = map[string]bool{
+	"steps": true, "timeout": true, "parallel": true, "seed": true, "mode": true, "m": true, "runes": true,
+	"assert": true, "calls": true, "debug": true, "numbers": true, "timing": true, "c": true, "s": true,
+	"t": true, "plot": true,
+}
+
+// configurableNames returns the flags in configurable, in the order flag.PrintDefaults lists them.
+func configurableNames() []string {
+	var names []string
+	flag.VisitAll(func(f *flag.Flag) {
+		if configurable[f.Name] {
+			names = append(names, "-"+f.Name)
+		}
+	})
+	return names
+}
+
+// configure fills in each flag which wasn't given on the command line from its environment variable, or
+// failing that from the configuration file. The file holds "name = value" lines, where name is the name of
+// a flag, optionally grouped under TOML [section] headers, e.g.
+//
+//	mode = "fishlanguage.com"
+//
+//	[limits]
+//	steps = 100000
+//	timeout = "10s"
+//
+// The file may only set the flags in configurable, and the flags in commandLineOnly are left alone.
+func configure() error {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	path, required := *configfile, true
+	if path == "" {
+		path, required = cli.ConfigName, false
+	}
+	values, err := cli.ReadConfig(path)
+	if os.IsNotExist(err) && !required {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		env := cli.EnvName(f.Name)
+		if commandLineOnly[f.Name] {
+			return
+		}
+		if v, ok := os.LookupEnv(env); ok && !given[f.Name] && err == nil {
+			if err = f.Value.Set(v); err != nil {
+				err = fmt.Errorf("%s: %v", env, err)
+			}
+			given[f.Name] = true
+		}
+	})
+	for _, v := range values {
+		if err != nil {
+			break
+		}
+		if f := flag.Lookup(v.Name); f == nil {
+			err = fmt.Errorf("%s:%d: unknown setting %q", path, v.Line, v.Name)
+		} else if commandLineOnly[v.Name] {
+			err = fmt.Errorf("%s:%d: %s can only be given on the command line", path, v.Line, v.Name)
+		} else if !configurable[v.Name] {
+			err = fmt.Errorf("%s:%d: %s can't be set in a configuration file", path, v.Line, v.Name)
+		} else if !given[v.Name] {
+			if err = f.Value.Set(v.Value); err != nil {
+				err = fmt.Errorf("%s:%d: %s: %v", path, v.Line, v.Name, err)
+			}
+		}
+	}
+	return err
+}
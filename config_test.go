@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/redstarcoder/go-fish/internal/cli"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigureAllowlist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, cli.ConfigName)
+	*configfile = path
+	defer func() {
+		*configfile, *maxsteps = "", 0
+	}()
+
+	for _, name := range []string{"code", "record", "thumbnail", "input", "challenge"} {
+		if err = ioutil.WriteFile(path, []byte("[run]\n"+name+" = \"x\"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		err = configure()
+		if want := path + ":2: " + name + " can't be set in a configuration file"; err == nil || err.Error() != want {
+			t.Errorf("got %v, want %q", err, want)
+		}
+	}
+	if err = ioutil.WriteFile(path, []byte("plugin = \"x.so\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = configure(); err == nil || !strings.Contains(err.Error(), "only be given on the command line") {
+		t.Errorf("got %v for plugin", err)
+	}
+
+	if err = ioutil.WriteFile(path, []byte("[limits]\nsteps = 1_000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = configure(); err != nil || *maxsteps != 1000 {
+		t.Errorf("got steps %d, %v", *maxsteps, err)
+	}
+}
@@ -0,0 +1,120 @@
+package fish
+
+// state is the position, direction and string mode of a ><>, which is everything needed to decide where
+// it may swim next without knowing the stack.
+type state struct {
+	x, y       int
+	dir        Direction
+	stringMode byte
+}
+
+// next returns the position of the cell n steps from (x,y) in direction d, wrapping around the codebox.
+func (cB *CodeBox) next(x, y int, d Direction, n int) (int, int) {
+	switch d {
+	case Right:
+		x = (x + n) % cB.width
+	case Down:
+		y = (y + n) % cB.height
+	case Left:
+		x = ((x-n)%cB.width + cB.width) % cB.width
+	case Up:
+		y = ((y-n)%cB.height + cB.height) % cB.height
+	}
+	return x, y
+}
+
+// explore calls visit once for every state the ><> may reach from its current state, ignoring the stack.
+// Branches ("x" and "?") are followed both ways, while paths which halt, jump with "." or hit an invalid
+// instruction end there. Changes made to the codebox with "p" are not taken into account.
+func (cB *CodeBox) explore(visit func(s state, r byte)) {
+	seen := make(map[state]bool)
+	queue := []state{{cB.fX, cB.fY, cB.fDir, cB.stringMode}}
+	push := func(s state, n int) {
+		s.x, s.y = cB.next(s.x, s.y, s.dir, n)
+		if !seen[s] {
+			seen[s] = true
+			queue = append(queue, s)
+		}
+	}
+	seen[queue[0]] = true
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		r := cB.box[s.y][s.x]
+		visit(s, r)
+		if s.stringMode != 0 {
+			if r == s.stringMode {
+				s.stringMode = 0
+			}
+			push(s, 1)
+			continue
+		}
+		switch r {
+		case ';', '.':
+		case '>':
+			push(state{s.x, s.y, Right, 0}, 1)
+		case 'v':
+			push(state{s.x, s.y, Down, 0}, 1)
+		case '<':
+			push(state{s.x, s.y, Left, 0}, 1)
+		case '^':
+			push(state{s.x, s.y, Up, 0}, 1)
+		case '|', '_', '#', '/', '\\':
+			push(state{s.x, s.y, s.dir.mirror(r), 0}, 1)
+		case 'x':
+			for d := Right; d <= Up; d++ {
+				push(state{s.x, s.y, d, 0}, 1)
+			}
+		case '"', '\'':
+			push(state{s.x, s.y, s.dir, r}, 1)
+		case '!':
+			push(s, 2)
+		case '?':
+			push(s, 1)
+			push(s, 2)
+		default:
+			if cB.isInstruction(r) {
+				push(s, 1)
+			}
+		}
+	}
+}
+
+// CellKind describes how the ><> may treat a cell. It is a set of flags, as a cell may be executed as an
+// instruction on one path and pushed as string data on another. A CellKind of 0 means the cell is
+// unreachable.
+type CellKind byte
+
+const (
+	Code CellKind = 1 << iota
+	String
+)
+
+func (k CellKind) String() string {
+	switch k {
+	case 0:
+		return "unreachable"
+	case Code:
+		return "code"
+	case String:
+		return "string"
+	}
+	return "code and string"
+}
+
+// CellKinds returns the CellKind of every cell in the codebox, indexed by [y][x], found by following every
+// path the ><> may swim from its current state. See explore for the limits of this analysis.
+func (cB *CodeBox) CellKinds() [][]CellKind {
+	kinds := make([][]CellKind, cB.height)
+	for y := range kinds {
+		kinds[y] = make([]CellKind, cB.width)
+	}
+	cB.explore(func(s state, r byte) {
+		if s.stringMode != 0 && r != s.stringMode {
+			kinds[s.y][s.x] |= String
+		} else {
+			kinds[s.y][s.x] |= Code
+		}
+	})
+	return kinds
+}
@@ -0,0 +1,28 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestCellKinds(t *testing.T) {
+	cB := NewCodeBox("\"ab\"\\\n    ;\n    o\n 'o'/", []float64{}, Spec)
+	want := [][]CellKind{
+		{Code, String, String, Code, Code},
+		{0, 0, 0, 0, Code},
+		{0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0},
+	}
+	kinds := cB.CellKinds()
+	for y := range want {
+		for x := range want[y] {
+			if kinds[y][x] != want[y][x] {
+				t.Errorf("kind of (%d,%d) = %d, want %d", x, y, kinds[y][x], want[y][x])
+			}
+		}
+	}
+
+	cB = NewCodeBox("x'a\n;", []float64{}, Spec)
+	if kinds = cB.CellKinds(); kinds[0][2] != Code|String {
+		t.Fail()
+	}
+}
@@ -0,0 +1,22 @@
+package fish
+
+// AssertExtension is an Extension which lets a ><> check its own state during development. It implements
+// "A", which pops a value and fails with the position of the "A" if the value is 0. Assertions can be
+// removed for release with StripAssertions.
+type AssertExtension struct{}
+
+// Instructions implements Extension.
+func (AssertExtension) Instructions() map[byte]Instruction {
+	return map[byte]Instruction{'A': assert}
+}
+
+// Manifest implements Declarer.
+func (AssertExtension) Manifest() Manifest {
+	return Manifest{Name: "AssertExtension", Ops: "A"}
+}
+
+func assert(cB *CodeBox) {
+	if cB.Pop() == 0 {
+		panic(failf(ErrAssertion, "Assertion failed at (%d,%d)!", cB.fX-cB.ox, cB.fY-cB.oy))
+	}
+}
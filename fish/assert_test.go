@@ -0,0 +1,33 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestAssertExtension(t *testing.T) {
+	cB := NewCodeBox("1A11=A0A;", nil, Spec, WithExtension(AssertExtension{}))
+	if got := swimAll(cB); got != "Assertion failed at (7,0)!" {
+		t.Errorf("got %q", got)
+	}
+	if cB.StackLength() != 0 {
+		t.Error(cB.Stack())
+	}
+}
+
+func TestStripAssertions(t *testing.T) {
+	p := testPreprocessor(map[string]string{
+		"main.fish": "0A\"A\"v\n;   A<",
+	})
+	StripAssertions()(p)
+	pp, err := p.run("main.fish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0~\"A\"v\n;   ~<"; pp.Script != want {
+		t.Errorf("got %q, want %q", pp.Script, want)
+	}
+	cB := NewCodeBox(pp.Script, nil, Spec)
+	if got := swimAll(cB); got != "" {
+		t.Error(got)
+	}
+}
@@ -0,0 +1,38 @@
+package fish
+
+import (
+	"testing"
+)
+
+// steady is a loop which exercises most stack instructions, and leaves the stack empty after each lap.
+const steady = ">123r{}$@:*+&&l[r]~~v\n^                   <"
+
+func BenchmarkSwim(b *testing.B) {
+	cB := NewCodeBox(steady, nil, Spec)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cB.Swim()
+	}
+}
+
+func BenchmarkSwimCompatibility(b *testing.B) {
+	cB := NewCodeBox(steady, nil, FishLanguageCom)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cB.Swim()
+	}
+}
+
+func TestSwimAllocs(t *testing.T) {
+	for _, mode := range []Mode{Spec, FishLanguageCom} {
+		cB := NewCodeBox(steady, nil, mode)
+		for i := 0; i < 1000; i++ {
+			cB.swim()
+		}
+		if n := testing.AllocsPerRun(1000, func() { cB.swim() }); n != 0 {
+			t.Errorf("%v mode: %v allocations per step", mode, n)
+		}
+	}
+}
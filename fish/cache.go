@@ -0,0 +1,67 @@
+package fish
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// ProgramCache holds the most recently used Programs, keyed by a hash of their script, so that a popular
+// script is only compiled once however many times it's run. It is safe for concurrent use.
+type ProgramCache struct {
+	size int
+
+	mu      sync.Mutex
+	lru     *list.List // Of *cached, most recently used first
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+type cached struct {
+	key [sha256.Size]byte
+	p   *Program
+}
+
+// NewProgramCache returns a ProgramCache which holds at most size Programs.
+func NewProgramCache(size int) *ProgramCache {
+	return &ProgramCache{size: size, lru: list.New(), entries: make(map[[sha256.Size]byte]*list.Element)}
+}
+
+// Get returns the Program for script, compiling it with Compile if it isn't cached. Scripts which fail to
+// compile aren't cached.
+func (c *ProgramCache) Get(script string, mode Mode) (*Program, error) {
+	key := sha256.Sum256(append([]byte{byte(mode)}, script...))
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*cached).p, nil
+	}
+	c.mu.Unlock()
+
+	// Compile without holding the lock, so a large script doesn't hold up the rest. If another goroutine
+	// compiles the same script meanwhile, the last one to finish is kept.
+	p, err := Compile(script, mode)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		e.Value.(*cached).p = p
+		return p, nil
+	}
+	c.entries[key] = c.lru.PushFront(&cached{key, p})
+	for c.lru.Len() > c.size {
+		delete(c.entries, c.lru.Remove(c.lru.Back()).(*cached).key)
+	}
+	return p, nil
+}
+
+// Len returns the number of Programs in the cache.
+func (c *ProgramCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
@@ -0,0 +1,29 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestProgramCache(t *testing.T) {
+	c := NewProgramCache(2)
+	a, err := c.Get("1n;", Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := c.Get("1n;", Spec); p != a {
+		t.Error("a cached script was compiled again")
+	}
+	if p, _ := c.Get("1n;", FishLanguageCom); p == a {
+		t.Error("fishlanguage.com mode shared a Program with spec mode")
+	}
+	c.Get("2n;", Spec) // Evicts "1n;" in Spec mode, the least recently used
+	if c.Len() != 2 {
+		t.Errorf("got %d cached programs, want 2", c.Len())
+	}
+	if p, _ := c.Get("1n;", Spec); p == a {
+		t.Error("the least recently used script wasn't evicted")
+	}
+	if _, err := c.Get("", Spec); err == nil || c.Len() != 2 {
+		t.Errorf("got %v with %d cached programs, want an error with 2", err, c.Len())
+	}
+}
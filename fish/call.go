@@ -0,0 +1,46 @@
+package fish
+
+import (
+	"errors"
+)
+
+// ErrNoCall is the cause of a Failure when "R" is executed without a matching "C".
+var ErrNoCall = errors.New("Nothing to return to!")
+
+// call is a return address pushed by "C".
+type call struct {
+	x, y int // The position of the "C", as seen by the ><>
+	dir  Direction
+}
+
+// CallExtension is an Extension which gives a ><> subroutines, as in starfish. It implements "C", which
+// pops y and then x, pushes the ><>'s position and direction onto a return stack kept apart from its
+// stacks, and jumps to (x,y) like ".", and "R", which pops the return stack and jumps back, so the ><>
+// resumes swimming in its old direction from just after the "C". "R" fails if the return stack is empty.
+type CallExtension struct{}
+
+// Instructions implements Extension.
+func (CallExtension) Instructions() map[byte]Instruction {
+	return map[byte]Instruction{'C': callSub, 'R': returnSub}
+}
+
+// Manifest implements Declarer.
+func (CallExtension) Manifest() Manifest {
+	return Manifest{Name: "CallExtension", Ops: "CR"}
+}
+
+func callSub(cB *CodeBox) {
+	y := toInt(cB.Pop(), "Coordinate")
+	x := toInt(cB.Pop(), "Coordinate")
+	cB.calls = append(cB.calls, call{cB.fX - cB.ox, cB.fY - cB.oy, cB.fDir})
+	cB.fX, cB.fY = x+cB.ox, y+cB.oy
+}
+
+func returnSub(cB *CodeBox) {
+	if len(cB.calls) == 0 {
+		panic(ErrNoCall)
+	}
+	c := cB.calls[len(cB.calls)-1]
+	cB.calls = cB.calls[:len(cB.calls)-1]
+	cB.fX, cB.fY, cB.fDir = c.x+cB.ox, c.y+cB.oy, c.dir
+}
@@ -0,0 +1,28 @@
+package fish
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCallExtension(t *testing.T) {
+	// The subroutine on the second row doubles the top of the stack, and is called twice
+	script := "01C01Cn;\n>:+R"
+	out := new(bytes.Buffer)
+	cB := NewCodeBox(script, []float64{3}, Spec, WithExtension(CallExtension{}), WithOutput(out))
+	if _, err := cB.Run(100); err != nil {
+		t.Fatal(err)
+	}
+	if got := cB.Stack(); len(got) != 0 || out.String() != "12" {
+		t.Errorf("got stack %v and output %q, want 12", got, out)
+	}
+
+	_, err := NewCodeBox("R", nil, Spec, WithExtension(CallExtension{})).Swim()
+	if !errors.Is(err, ErrNoCall) {
+		t.Errorf("got %v, want ErrNoCall", err)
+	}
+	if _, err := NewCodeBox("01C;", nil, Spec).Run(10); err == nil {
+		t.Error("C was executed without CallExtension")
+	}
+}
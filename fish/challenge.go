@@ -0,0 +1,119 @@
+package fish
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Case is a single test of a Challenge: the ><> must write Output when started with Stack and Input.
+type Case struct {
+	Stack  []float64 `json:"stack"`
+	Input  string    `json:"input"`
+	Output string    `json:"output"`
+}
+
+// Challenge is a set of constraints a solution must meet, usually read from JSON with ReadChallenge:
+//
+//	{
+//		"name": "Hello",
+//		"maxWidth": 20, "maxHeight": 1,
+//		"forbidden": "p",
+//		"quotas": {"on": 12},
+//		"maxSteps": 1000,
+//		"assert": ["depth == 0"],
+//		"cases": [{"output": "Hello World"}]
+//	}
+//
+// Zero limits are not enforced.
+type Challenge struct {
+	Name      string         `json:"name"`
+	MaxWidth  int            `json:"maxWidth"`
+	MaxHeight int            `json:"maxHeight"`
+	Forbidden string         `json:"forbidden"` // Instructions which may not appear outside of strings
+	Quotas    map[string]int `json:"quotas"`    // See WithQuota
+	MaxSteps  int            `json:"maxSteps"`  // Per case; solutions which don't halt in time fail
+	Assert    []string       `json:"assert"`    // Watch expressions which must be true when each case halts
+	Cases     []Case         `json:"cases"`
+}
+
+// defaultMaxSteps stops solutions which never halt when a Challenge doesn't set MaxSteps.
+const defaultMaxSteps = 1000000
+
+// ReadChallenge reads a JSON Challenge from r.
+func ReadChallenge(r io.Reader) (*Challenge, error) {
+	c := new(Challenge)
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("challenge: %v", err)
+	}
+	return c, nil
+}
+
+// Check runs script against the Challenge, returning the total number of steps it took to pass every
+// case, or an error describing the first constraint it broke.
+func (c *Challenge) Check(script string) (steps int, err error) {
+	p, err := Compile(script, Spec)
+	if err != nil {
+		return 0, err
+	}
+	cB := p.New(nil)
+	if c.MaxWidth > 0 && cB.width > c.MaxWidth || c.MaxHeight > 0 && cB.height > c.MaxHeight {
+		return 0, fmt.Errorf("codebox is %dx%d, the limit is %dx%d", cB.width, cB.height, c.MaxWidth,
+			c.MaxHeight)
+	}
+	kinds := cB.CellKinds()
+	for y, line := range cB.box {
+		for x, r := range line {
+			if kinds[y][x] != String && strings.IndexByte(c.Forbidden, r) >= 0 {
+				return 0, fmt.Errorf("forbidden instruction %q at (%d,%d)", r, x, y)
+			}
+		}
+	}
+
+	asserts := make([]Probe, len(c.Assert))
+	for i, expr := range c.Assert {
+		if asserts[i], err = ParseExpr(expr); err != nil {
+			return 0, fmt.Errorf("challenge: %v", err)
+		}
+	}
+
+	maxSteps := c.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = defaultMaxSteps
+	}
+	for i, tc := range c.Cases {
+		var log EventLog
+		opts := []Option{WithInput(strings.NewReader(tc.Input)), WithDeterministic(),
+			WithOutput(ioutil.Discard), WithEventLog(&log)}
+		if c.Forbidden != "" {
+			opts = append(opts, WithQuota(c.Forbidden, 0))
+		}
+		for ops, max := range c.Quotas {
+			opts = append(opts, WithQuota(ops, max))
+		}
+		cB := p.New(tc.Stack, opts...)
+		n, done, fail := 0, false, ""
+		for ; n < maxSteps && !done && fail == ""; n++ {
+			done, fail = swimRecover(cB)
+		}
+		switch {
+		case fail != "":
+			return steps, fmt.Errorf("case %d: %s", i+1, fail)
+		case !done:
+			return steps, fmt.Errorf("case %d: did not halt within %d steps", i+1, maxSteps)
+		case log.Output() != tc.Output:
+			return steps, fmt.Errorf("case %d: wrote %q, want %q", i+1, log.Output(), tc.Output)
+		}
+		for ii, assert := range asserts {
+			if assert(cB) == 0 {
+				return steps, fmt.Errorf("case %d: assertion %q failed", i+1, c.Assert[ii])
+			}
+		}
+		steps += n
+	}
+	return steps, nil
+}
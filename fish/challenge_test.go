@@ -0,0 +1,50 @@
+package fish
+
+import (
+	"strings"
+	"testing"
+)
+
+const testChallenge = `{
+	"name": "Double",
+	"maxWidth": 8, "maxHeight": 1,
+	"forbidden": "p",
+	"maxSteps": 50,
+	"cases": [{"stack": [2], "output": "4"}, {"stack": [5], "output": "10"}]
+}`
+
+func TestChallenge(t *testing.T) {
+	c, err := ReadChallenge(strings.NewReader(testChallenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "Double" || len(c.Cases) != 2 || c.Cases[1].Stack[0] != 5 {
+		t.Fatalf("%+v", c)
+	}
+
+	for _, test := range []struct {
+		script, err string
+		steps       int
+	}{
+		{"2*n;", "", 8},
+		{":+n;", "", 8},
+		{"2*n   ;;;", "codebox is 9x1, the limit is 8x1", 0},
+		{"2*n;\n;", "codebox is 4x2, the limit is 8x1", 0},
+		{"'p'~2*n;", "", 16},
+		{"2*n;p", "forbidden instruction 'p' at (4,0)", 0},
+		{"3*n;", `case 1: wrote "6", want "4"`, 0},
+		{"2*n>30.", "case 1: did not halt within 50 steps", 0},
+		{"~", "case 1: Stack is empty!", 0},
+	} {
+		steps, err := c.Check(test.script)
+		if err == nil && test.err != "" || err != nil && err.Error() != test.err {
+			t.Errorf("%q: got error %v, want %q", test.script, err, test.err)
+		} else if err == nil && steps != test.steps {
+			t.Errorf("%q: got %d steps, want %d", test.script, steps, test.steps)
+		}
+	}
+
+	if _, err := ReadChallenge(strings.NewReader(`{"maxwidht": 3}`)); err == nil {
+		t.Error("unknown fields should be rejected")
+	}
+}
@@ -0,0 +1,44 @@
+package fish
+
+// Clone returns a copy of the CodeBox which can swim independently of it, for exploring forked executions.
+// The codebox is copied on write: rows are shared until either CodeBox modifies them with "p", so cloning
+// is cheap however large the codebox is. Stacks, and the return stack of CallExtension, are copied.
+//
+// Observers, narration, event logs, taint tracking and replayed input are not carried over, while input,
+// output and the source of randomness are shared with the original. opts are applied to the clone, and may
+// be used to attach new ones.
+func (cB *CodeBox) Clone(opts ...Option) *CodeBox {
+	c := *cB
+	c.observers, c.narration, c.events, c.taint, c.replay = nil, nil, nil, nil, nil
+	c.box = append([][]byte(nil), cB.box...)
+	cB.shared = make([]bool, len(cB.box))
+	for i := range cB.shared {
+		cB.shared[i] = true
+	}
+	c.shared = append([]bool(nil), cB.shared...)
+	c.stacks = make([]*Stack, len(cB.stacks))
+	for i, s := range cB.stacks {
+		c.stacks[i] = &Stack{S: append([]float64(nil), s.S...), register: s.register,
+			filledRegister: s.filledRegister}
+	}
+	c.frames = append([]Frame(nil), cB.frames...)
+	c.calls = append([]call(nil), cB.calls...)
+	if cB.wide != nil {
+		c.wide = make(map[[2]int]rune, len(cB.wide))
+		for p, r := range cB.wide {
+			c.wide[p] = r
+		}
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &c
+}
+
+// own makes row y of the codebox safe to modify, copying it if it is shared with a clone.
+func (cB *CodeBox) own(y int) {
+	if y < len(cB.shared) && cB.shared[y] {
+		cB.box[y] = append([]byte(nil), cB.box[y]...)
+		cB.shared[y] = false
+	}
+}
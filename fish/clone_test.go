@@ -0,0 +1,43 @@
+package fish
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	cB := NewCodeBox("11[3]&a00p;\n;", nil, Spec)
+	for i := 0; i < 5; i++ {
+		cB.swim()
+	}
+	c := cB.Clone()
+	if &c.box[1][0] != &cB.box[1][0] {
+		t.Error("the codebox wasn't shared")
+	}
+	for !c.swim() {
+	}
+	if c.box[0][0] != 10 || cB.box[0][0] != '1' {
+		t.Errorf("writes weren't copied: %q, %q", c.box[0][0], cB.box[0][0])
+	}
+	if &c.box[1][0] != &cB.box[1][0] {
+		t.Error("an unmodified row was copied")
+	}
+	if !sameValues(c.Stack(), []float64{1}) || !c.stacks[0].filledRegister || cB.stacks[0].filledRegister {
+		t.Errorf("clone: %v, original: %v", c.Stack(), cB.Stack())
+	}
+
+	for !cB.swim() {
+	}
+	cB.put(0, 1, 'x')
+	if c.box[1][0] != ';' {
+		t.Error("the original's writes were seen by the clone")
+	}
+
+	var log EventLog
+	c = NewCodeBox("in;", nil, Spec, WithInput(strings.NewReader("a"))).Clone(WithEventLog(&log))
+	for !c.swim() {
+	}
+	if log.Output() != "97" {
+		t.Errorf("got %q", log.Output())
+	}
+}
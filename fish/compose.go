@@ -0,0 +1,161 @@
+package fish
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitScript returns the lines of script, as NewCodeBox would see them.
+func splitScript(script string) []string {
+	return strings.Split(strings.Replace(script, "\r", "", -1), "\n")
+}
+
+// literalFor returns the instruction which pushes v, and whether v can be pushed by a single instruction,
+// which it can only be from 0 to 15.
+func literalFor(v int) (byte, bool) {
+	switch {
+	case v >= 0 && v <= 9:
+		return byte('0' + v), true
+	case v >= 10 && v <= 15:
+		return byte('a' + v - 10), true
+	}
+	return 0, false
+}
+
+// pushInt returns ><> code which pushes the non-negative integer n.
+func pushInt(n int) string {
+	if r, ok := literalFor(n); ok {
+		return string(r)
+	}
+	s := pushInt(n/15) + "f*"
+	if r, _ := literalFor(n % 15); n%15 != 0 {
+		s += string(r) + "+"
+	}
+	return s
+}
+
+// Jump returns ><> code which jumps to (x,y). As with any ".", the ><> then moves before executing its next
+// instruction, so glue code should jump to the cell before the one it means to execute.
+func Jump(x, y int) string {
+	return pushInt(x) + pushInt(y) + "."
+}
+
+// Compose places scripts side by side in a single codebox, so that routines written separately can be
+// used together, and returns it along with the x coordinate at which each script was placed. The ><>
+// starts in the first script, and Jump can be used to swim from one script to another.
+//
+// Each script is moved with Relocate, and glue code is generated on either side of it, which jumps a ><>
+// swimming off one side of the script back to the other, so it wraps around the script as it did on its
+// own. Warnings are returned, with their positions in the new codebox, for coordinates which can't be
+// relocated, and for places where the ><> may cross the edge of a script in string mode or by skipping over
+// it, as the glue, or the blank rows below a shorter script, would then change what it does.
+func Compose(scripts ...string) (string, []int, []Warning, error) {
+	// left and right are the widths of the glue on each side of each script. They're grown until each
+	// jump fits, as moving the scripts changes the coordinates they jump to.
+	left, right := make([]int, len(scripts)), make([]int, len(scripts))
+	for {
+		boxes := make([]*relocation, len(scripts))
+		origins := make([]int, len(scripts))
+		height, x := 0, 0
+		for i, script := range scripts {
+			if i > 0 {
+				x += left[i]
+			}
+			origins[i] = x
+			r, err := relocate(script, x, 0, false)
+			if err != nil {
+				return "", nil, nil, fmt.Errorf("script %d: %v", i, err)
+			}
+			boxes[i] = r
+			if len(r.lines) > height {
+				height = len(r.lines)
+			}
+			x += len(r.lines[0]) + right[i]
+		}
+		// The first script's left glue goes at the far right, where the ><> wraps to
+		width := x + left[0]
+
+		grown := false
+		glue := make([][2][]string, len(scripts))
+		for i, r := range boxes {
+			for y := range r.lines {
+				// The ><> moves after jumping, so jump to the cell before the other side of the script
+				toLeft := Jump((origins[i]-1+width)%width, y)
+				toRight := Jump(origins[i]+len(r.lines[0]), y)
+				glue[i][0] = append(glue[i][0], reverse(toRight))
+				glue[i][1] = append(glue[i][1], toLeft)
+				if len(toRight) > left[i] {
+					left[i], grown = len(toRight), true
+				}
+				if len(toLeft) > right[i] {
+					right[i], grown = len(toLeft), true
+				}
+			}
+		}
+		if grown {
+			continue
+		}
+
+		rows := make([]string, height)
+		for y := range rows {
+			var row strings.Builder
+			for i, r := range boxes {
+				if i > 0 {
+					row.WriteString(glueCell(glue[i][0], y, left[i], true))
+				}
+				if y < len(r.lines) {
+					row.WriteString(r.lines[y])
+				} else {
+					row.WriteString(strings.Repeat(" ", len(r.lines[0])))
+				}
+				row.WriteString(glueCell(glue[i][1], y, right[i], false))
+			}
+			row.WriteString(glueCell(glue[0][0], y, left[0], true))
+			rows[y] = row.String()
+		}
+
+		var warnings []Warning
+		for i, r := range boxes {
+			for _, w := range r.warnings {
+				w.X += origins[i]
+				warnings = append(warnings, w)
+			}
+			cB := NewCodeBox(scripts[i], nil, Spec)
+			states := cB.reachable()
+			for _, vertical := range []bool{false, true} {
+				if vertical && len(r.lines) == height {
+					continue
+				}
+				if s, ok := cB.crossing(states, 0, vertical); ok {
+					x, y := r.pos(s.x, s.y)
+					warnings = append(warnings, Warning{x + origins[i], y, "the ><> may cross the edge of its " +
+						"script in string mode or by skipping over it, which won't wrap around as it did"})
+				}
+			}
+		}
+		return strings.Join(rows, "\n"), origins, warnings, nil
+	}
+}
+
+// glueCell returns row y of the glue code in rows, padded to width. Glue on the left of a script is read
+// by a ><> swimming left, so it's aligned right.
+func glueCell(rows []string, y, width int, alignRight bool) string {
+	var code string
+	if y < len(rows) {
+		code = rows[y]
+	}
+	pad := strings.Repeat(" ", width-len(code))
+	if alignRight {
+		return pad + code
+	}
+	return code + pad
+}
+
+// reverse returns s backwards, so that a ><> swimming left executes it in order.
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
@@ -0,0 +1,49 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestCompose(t *testing.T) {
+	// The second script jumps past x=15 once moved, and both wrap around themselves
+	script, origins, warnings, err := Compose("<;n1", "a1.\n;          5n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(origins) != 2 || origins[0] != 0 || origins[1] <= 15 || len(warnings) != 0 {
+		t.Fatal(origins, warnings)
+	}
+	if got := runOutput(script); got != "1" {
+		t.Errorf("got %q from the first script, want \"1\"", got)
+	}
+	var log EventLog
+	cB := NewCodeBox(script, nil, Spec, WithEventLog(&log))
+	cB.fX = origins[1]
+	for i := 0; i < 1000 && !cB.swim(); i++ {
+	}
+	if got := log.Output(); got != "5" {
+		t.Errorf("got %q from the second script, want \"5\"", got)
+	}
+
+	_, _, warnings, err = Compose("1n", "\"ao;")
+	if err != nil || len(warnings) != 1 || warnings[0].Y != 0 {
+		t.Error(warnings, err)
+	}
+}
+
+func TestJump(t *testing.T) {
+	for _, n := range []int{0, 15, 16, 30, 100, 241} {
+		cB := runscript(pushInt(n)+";", []float64{}, Spec)
+		if s := cB.Stack(); len(s) != 1 || s[0] != float64(n) {
+			t.Errorf("pushInt(%d) pushed %v", n, s)
+		}
+	}
+
+	cB := NewCodeBox(Jump(20, 1)+";\n"+"                     1;", []float64{}, Spec)
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if s := cB.Stack(); len(s) != 1 || s[0] != 1 {
+		t.Fail()
+	}
+}
@@ -0,0 +1,23 @@
+package fish
+
+import (
+	"math"
+)
+
+// toInt converts v, which is used as what, to an int, discarding any fractional part. It panics if v is NaN
+// or is too large to be converted exactly.
+func toInt(v float64, what string) int {
+	if math.IsNaN(v) || v >= 1<<53 || v <= -1<<53 {
+		panic(failf(ErrOutOfRange, "%s %v is out of range!", what, v))
+	}
+	return int(v)
+}
+
+// toByte converts v to a byte which can be stored in the codebox, discarding any fractional part. It panics
+// if v is NaN or outside of 0-255.
+func toByte(v float64) byte {
+	if math.IsNaN(v) || v < 0 || v >= 256 {
+		panic(failf(ErrCellValue, "Value %v cannot be stored in the codebox!", v))
+	}
+	return byte(v)
+}
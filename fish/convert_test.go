@@ -0,0 +1,31 @@
+package fish
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCheckedConversions(t *testing.T) {
+	tests := []struct {
+		script string
+		stack  []float64
+		want   string
+	}{
+		{"g;", []float64{0, math.NaN()}, "Coordinate NaN is out of range!"},
+		{".", []float64{math.Inf(1), 0}, "Coordinate +Inf is out of range!"},
+		{"p;", []float64{-1, 0, 0}, "Value -1 cannot be stored in the codebox!"},
+		{"p;", []float64{300, 0, 0}, "Value 300 cannot be stored in the codebox!"},
+		{"%;", []float64{1e300, 7}, "Dividend 1e+300 is out of range!"},
+		{"%;", []float64{5, 0.5}, "Division by zero!"},
+	}
+	for _, test := range tests {
+		if fail := swimAll(NewCodeBox(test.script, test.stack, Spec)); fail != test.want {
+			t.Errorf("%q with %v: got %q, want %q", test.script, test.stack, fail, test.want)
+		}
+	}
+
+	cB := NewCodeBox("%;", []float64{5.5, 2}, FishLanguageCom)
+	if swimAll(cB) != "" || cB.Pop() != 1.5 {
+		t.Fail()
+	}
+}
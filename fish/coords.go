@@ -0,0 +1,129 @@
+package fish
+
+import (
+	"bytes"
+)
+
+// Negative is how "g" and "p" treat negative coordinates.
+type Negative byte
+
+const (
+	NegativeError Negative = iota // Negative coordinates are an error
+	NegativeWrap                  // Negative coordinates wrap around the codebox, as the ><> does
+	// NegativeGrow grows the codebox up or left when "p" writes to a negative coordinate, keeping the
+	// coordinates of existing cells the same, while "g" pushes 0 for cells which don't exist. This is how
+	// fishlanguage.com and fishinterpreter.com behave, and is the default in their Modes.
+	NegativeGrow
+)
+
+// WithNegativeCoordinates sets how "g" and "p" treat negative coordinates. The default is NegativeError,
+// or NegativeGrow in the FishLanguageCom and FishInterpreterCom Modes.
+func WithNegativeCoordinates(n Negative) Option {
+	return func(cB *CodeBox) {
+		cB.negative = n
+	}
+}
+
+// resolve returns the position in the codebox of (x,y) as seen by the ><>, and whether the cell exists.
+// If grow is set and the cell doesn't exist, the codebox is grown to make it exist.
+func (cB *CodeBox) resolve(x, y int, grow bool) (int, int, bool) {
+	bx, by := x+cB.ox, y+cB.oy
+	if bx >= 0 && by >= 0 {
+		return bx, by, true
+	}
+	switch cB.negative {
+	case NegativeWrap:
+		return (bx%cB.width + cB.width) % cB.width, (by%cB.height + cB.height) % cB.height, true
+	case NegativeGrow:
+		if !grow {
+			return 0, 0, false
+		}
+		dx, dy := 0, 0
+		if bx < 0 {
+			dx = -bx
+		}
+		if by < 0 {
+			dy = -by
+		}
+		cB.checkSize(x, y, cB.width+dx, cB.height+dy)
+		cB.grow(dx, dy)
+		return bx + dx, by + dy, true
+	}
+	panic(failf(ErrOutOfBounds, "Coordinate (%d,%d) is negative!", x, y))
+}
+
+// grow adds dx columns to the left of the codebox and dy rows above it, without changing the coordinates
+// of existing cells as seen by the ><>.
+func (cB *CodeBox) grow(dx, dy int) {
+	cB.width += dx
+	box := make([][]byte, dy, cB.height+dy)
+	for i := range box {
+		box[i] = bytes.Repeat([]byte{' '}, cB.width)
+	}
+	for _, line := range cB.box {
+		box = append(box, append(bytes.Repeat([]byte{' '}, dx), line...))
+	}
+	cB.box, cB.shared = box, nil
+	if cB.wide != nil {
+		wide := make(map[[2]int]rune, len(cB.wide))
+		for c, r := range cB.wide {
+			wide[[2]int{c[0] + dx, c[1] + dy}] = r
+		}
+		cB.wide = wide
+	}
+	cB.height += dy
+	cB.ox, cB.oy = cB.ox+dx, cB.oy+dy
+	cB.fX, cB.fY = cB.fX+dx, cB.fY+dy
+}
+
+// maxCells is the largest area "p" may grow the codebox to.
+const maxCells = 1 << 24
+
+// checkSize panics if growing the codebox to width by height, to write to (x,y), would make it too large.
+func (cB *CodeBox) checkSize(x, y, width, height int) {
+	// Checked so that width*height can't overflow
+	if width > maxCells || height > maxCells || width > maxCells/height {
+		panic(failf(ErrOutOfBounds, "Coordinate (%d,%d) is too far outside the codebox!", x, y))
+	}
+}
+
+// extend adds columns to the right of the codebox and rows below it, so that it's width by height.
+func (cB *CodeBox) extend(width, height int) {
+	if width > cB.width {
+		for i, line := range cB.box {
+			cB.box[i] = append(line[:len(line):len(line)], bytes.Repeat([]byte{' '}, width-cB.width)...)
+		}
+		cB.shared = nil
+		cB.width = width
+	}
+	for ; cB.height < height; cB.height++ {
+		cB.box = append(cB.box, bytes.Repeat([]byte{' '}, cB.width))
+	}
+}
+
+// get implements "g" for (x,y), returning 0 for cells outside the codebox.
+func (cB *CodeBox) get(x, y int) rune {
+	if x, y, ok := cB.resolve(x, y, false); ok && x < cB.width && y < cB.height {
+		return cB.cell(x, y)
+	}
+	return 0
+}
+
+// put implements "p" for (x,y), growing the codebox right or down if (x,y) is beyond it.
+func (cB *CodeBox) put(x, y int, v rune) {
+	bx, by, _ := cB.resolve(x, y, true)
+	if bx >= cB.width || by >= cB.height {
+		w, h := cB.width, cB.height
+		if bx >= w {
+			w = bx + 1
+		}
+		if by >= h {
+			h = by + 1
+		}
+		cB.checkSize(x, y, w, h)
+		cB.extend(w, h)
+	}
+	x, y = bx, by
+	cB.own(y)
+	cB.set(x, y, v)
+}
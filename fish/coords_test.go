@@ -0,0 +1,80 @@
+package fish
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNegativeCoordinates(t *testing.T) {
+	if fail := swimAll(NewCodeBox("01-0g;", []float64{}, Spec)); fail != "Coordinate (-1,0) is negative!" {
+		t.Error(fail)
+	}
+
+	cB := NewCodeBox("01-0g;", []float64{}, Spec, WithNegativeCoordinates(NegativeWrap))
+	if swimAll(cB) != "" || cB.Pop() != ';' {
+		t.Fail()
+	}
+
+	cB = NewCodeBox("a01-01-p01-01-g00g;", []float64{}, FishLanguageCom)
+	if swimAll(cB) != "" || cB.width != 20 || cB.height != 2 || cB.box[0][0] != 10 {
+		t.FailNow()
+	}
+	if s := cB.Stack(); len(s) != 2 || s[0] != 10 || s[1] != 'a' {
+		t.Fail()
+	}
+	cB = NewCodeBox("02-0g;", []float64{}, FishLanguageCom)
+	if swimAll(cB) != "" || cB.Pop() != 0 || cB.width != 6 {
+		t.Fail()
+	}
+
+	cB = NewCodeBox("';'01-0p02-0.", []float64{}, FishLanguageCom)
+	if swimAll(cB) != "" || cB.box[0][0] != ';' || cB.fX != 0 {
+		t.Fail()
+	}
+}
+
+func TestGetOutOfRange(t *testing.T) {
+	for _, mode := range []Mode{Spec, FishLanguageCom, FishInterpreterCom} {
+		cB := NewCodeBox("f0g0fga0g; ", []float64{}, mode)
+		if swimAll(cB) != "" {
+			t.FailNow()
+		}
+		// The space is an empty cell on the websites.
+		want := []float64{0, 0, ' '}
+		if mode != Spec {
+			want[2] = 0
+		}
+		if s := cB.Stack(); !sameValues(s, want) {
+			t.Errorf("%v mode: got stack %v, want %v", mode, s, want)
+		}
+	}
+}
+
+func TestPutGrows(t *testing.T) {
+	cB := NewCodeBox("'a'ff+0p'b'05pff+0g05g;", []float64{}, Spec)
+	c := cB.Clone()
+	if swimAll(cB) != "" {
+		t.FailNow()
+	}
+	if cB.width != 31 || cB.height != 6 || cB.box[0][30] != 'a' || cB.box[5][0] != 'b' || cB.box[1][30] != ' ' {
+		t.Errorf("got a %dx%d codebox %q", cB.width, cB.height, cB.box)
+	}
+	if s := cB.Stack(); !sameValues(s, []float64{'a', 'b'}) {
+		t.Errorf("got stack %v, want [%d %d]", s, 'a', 'b')
+	}
+	if c.width != 23 || c.height != 1 {
+		t.Errorf("the clone's codebox grew to %dx%d", c.width, c.height)
+	}
+
+	if fail := swimAll(NewCodeBox("0ff*f*f*:p;", []float64{}, Spec)); fail !=
+		"Coordinate (50625,50625) is too far outside the codebox!" {
+		t.Error(fail)
+	}
+
+	// 2^32-1 squared wraps to a small number in 64 bits
+	huge := "1" + strings.Repeat("2"+strings.Repeat("2", 31)+strings.Repeat("*", 31)+"1-", 2) + "p;"
+	if fail := swimAll(NewCodeBox(huge, []float64{}, Spec)); fail !=
+		"Coordinate (4294967295,4294967295) is too far outside the codebox!" {
+		t.Error(fail)
+	}
+}
@@ -0,0 +1,31 @@
+package fish
+
+import (
+	"fmt"
+	"io"
+)
+
+// DebugExtension is an Extension which gives a ><> a way to print debugging information without affecting
+// its output. It implements "D", which writes the position of the "D" and the current stack to W, or to
+// the CodeBox's diagnostics writer if W is nil, leaving the stack unchanged.
+type DebugExtension struct {
+	W io.Writer
+}
+
+// Instructions implements Extension.
+func (e DebugExtension) Instructions() map[byte]Instruction {
+	return map[byte]Instruction{'D': e.debug}
+}
+
+// Manifest implements Declarer.
+func (e DebugExtension) Manifest() Manifest {
+	return Manifest{Name: "DebugExtension", Ops: "D"}
+}
+
+func (e DebugExtension) debug(cB *CodeBox) {
+	w := e.W
+	if w == nil {
+		w = cB.diag()
+	}
+	fmt.Fprintf(w, "D at (%d,%d): %s\n", cB.fX-cB.ox, cB.fY-cB.oy, formatStack(cB.Stack()))
+}
@@ -0,0 +1,21 @@
+package fish
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDebugExtension(t *testing.T) {
+	buf := new(bytes.Buffer)
+	var log EventLog
+	cB := NewCodeBox("D12D+Dn;", nil, Spec, WithExtension(DebugExtension{buf}), WithEventLog(&log))
+	if got := swimAll(cB); got != "" {
+		t.Fatal(got)
+	}
+	if want := "D at (0,0): empty\nD at (3,0): 1 2\nD at (5,0): 3\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf, want)
+	}
+	if log.Output() != "3" {
+		t.Errorf("output was %q", log.Output())
+	}
+}
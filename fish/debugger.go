@@ -0,0 +1,485 @@
+package fish
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// debuggerHelp lists the commands understood by Debugger.Serve.
+const debuggerHelp = `Commands:
+  s, step [n]        execute n ticks (default 1)
+  c, continue        run until a breakpoint, or until the fish halts or fails
+  b, break [x,y]     set a breakpoint at (x,y), or list the breakpoints
+  b x,y if <expr>    set a breakpoint at (x,y) which stops only when the watch expression expr is true,
+                     e.g. b 3,0 if depth > 2 && stack[0] == 10
+  d, delete x,y      remove the breakpoint at (x,y)
+  p, print           show the position, direction, stacks and register
+  box                show the codebox
+  m, mark [name]     bookmark the fish's position as name, or list the bookmarks
+  m, mark name x,y   bookmark (x,y) as name
+  g, goto <where>    show the codebox around where, without moving the fish: a bookmark, x,y, "output"
+                     for the last o or n executed, "write" for the cell last written by p, or "fish"
+  h, help            show this message
+  q, quit            stop debugging
+An empty line repeats the last command. A continue which doesn't stop within a million ticks pauses, so a
+fish which never reaches a breakpoint can still be inspected.`
+
+// continueSteps is the most ticks Continue executes at once.
+const continueSteps = 1000000
+
+// viewWidth and viewHeight are the most columns and rows of the codebox the debugger shows at once.
+const (
+	viewWidth  = 40
+	viewHeight = 20
+)
+
+// breakpoint is a breakpoint set on a cell, which stops the ><> when cond is true, or always if cond is nil.
+type breakpoint struct {
+	cond Probe
+	expr string // The watch expression cond was parsed from
+}
+
+// Debugger runs a CodeBox interactively, one command at a time, stopping at breakpoints set on cells of the
+// codebox. Its prompts and reports, including the codebox after each command which moves the ><>, go to the
+// CodeBox's diagnostics writer.
+type Debugger struct {
+	cB     *CodeBox
+	breaks map[[2]int]breakpoint
+	err    error // The *Failure the ><> stopped with, if any
+	done   bool
+	marks  map[string][2]int
+	view   *[2]int // The cell shown by goto, until the ><> next moves
+	output *[2]int // Where the last "o" or "n" was executed
+	write  *[2]int // The cell last written by "p"
+}
+
+// NewDebugger returns a Debugger for cB, with no breakpoints.
+func NewDebugger(cB *CodeBox) *Debugger {
+	return &Debugger{cB: cB, breaks: make(map[[2]int]breakpoint), marks: make(map[string][2]int)}
+}
+
+// Bookmark names the cell (x,y), as seen by the ><>, so "goto name" shows the codebox around it.
+func (d *Debugger) Bookmark(name string, x, y int) {
+	d.marks[name] = [2]int{x, y}
+}
+
+// LastOutput returns where the last "o" or "n" was executed, and false if none has been.
+func (d *Debugger) LastOutput() (x, y int, ok bool) {
+	if d.output == nil {
+		return 0, 0, false
+	}
+	return d.output[0], d.output[1], true
+}
+
+// LastWrite returns the cell last written by "p", and false if none has been.
+func (d *Debugger) LastWrite() (x, y int, ok bool) {
+	if d.write == nil {
+		return 0, 0, false
+	}
+	return d.write[0], d.write[1], true
+}
+
+// Break sets a breakpoint at (x,y), as seen by the ><>. Continue stops before executing the cell.
+func (d *Debugger) Break(x, y int) {
+	d.breaks[[2]int{x, y}] = breakpoint{}
+}
+
+// BreakIf sets a conditional breakpoint at (x,y), where Continue only stops if the watch expression expr,
+// as parsed by ParseExpr, is true: non-zero and not NaN.
+func (d *Debugger) BreakIf(x, y int, expr string) error {
+	cond, err := ParseExpr(expr)
+	if err != nil {
+		return err
+	}
+	d.breaks[[2]int{x, y}] = breakpoint{cond, expr}
+	return nil
+}
+
+// Delete removes the breakpoint at (x,y), returning false if there was none.
+func (d *Debugger) Delete(x, y int) bool {
+	_, ok := d.breaks[[2]int{x, y}]
+	delete(d.breaks, [2]int{x, y})
+	return ok
+}
+
+// hit reports whether the ><> is on a breakpoint whose condition, if it has one, is true.
+func (d *Debugger) hit() bool {
+	x, y := d.cB.Position()
+	b, ok := d.breaks[[2]int{x, y}]
+	if !ok || b.cond == nil {
+		return ok
+	}
+	v := b.cond(d.cB)
+	return v != 0 && v == v
+}
+
+// Breakpoints returns the breakpoints, ordered row by row.
+func (d *Debugger) Breakpoints() [][2]int {
+	bs := make([][2]int, 0, len(d.breaks))
+	for b := range d.breaks {
+		bs = append(bs, b)
+	}
+	sort.Slice(bs, func(i, j int) bool {
+		return bs[i][1] < bs[j][1] || bs[i][1] == bs[j][1] && bs[i][0] < bs[j][0]
+	})
+	return bs
+}
+
+// Step executes up to n ticks, stopping early if the ><> halts or fails. It returns true once the ><> has
+// halted, and the *Failure if it failed; either way, later calls do nothing.
+func (d *Debugger) Step(n int) (done bool, err error) {
+	for ; n > 0 && !d.done && d.err == nil; n-- {
+		d.swim()
+	}
+	return d.done, d.err
+}
+
+// Continue executes ticks until the ><> reaches a breakpoint, halts or fails. It always executes at least
+// one tick, so it can continue from a breakpoint. If it executes a million ticks without stopping, it
+// pauses and returns ErrMaxSteps, which doesn't stop the ><> from being continued again.
+func (d *Debugger) Continue() (done bool, err error) {
+	for n := 0; !d.done && d.err == nil; n++ {
+		if n > 0 && d.hit() {
+			break
+		}
+		if n == continueSteps {
+			return false, ErrMaxSteps
+		}
+		d.swim()
+	}
+	return d.done, d.err
+}
+
+// swim executes a tick, remembering where the ><> output, or which cell it wrote to.
+func (d *Debugger) swim() {
+	cB := d.cB
+	x, y := cB.Position()
+	op, s := cB.cell(cB.fX, cB.fY), cB.Stack()
+	if cB.stringMode != 0 {
+		op = 0
+	}
+	var write *[2]int
+	if op == 'p' && len(s) >= 3 {
+		write = &[2]int{int(s[len(s)-2]), int(s[len(s)-1])}
+	}
+	d.view = nil
+	if d.done, d.err = cB.Swim(); d.err != nil {
+		return
+	}
+	if op == 'o' || op == 'n' {
+		d.output = &[2]int{x, y}
+	} else if write != nil {
+		d.write = write
+	}
+}
+
+// Serve reads commands from r, one per line, until "quit" or the end of r, prompting for each on the
+// diagnostics writer. It only returns an error if r can't be read.
+func (d *Debugger) Serve(r io.Reader) error {
+	w := d.cB.diag()
+	sc := bufio.NewScanner(r)
+	last := ""
+	for fmt.Fprint(w, "(fish) "); sc.Scan(); fmt.Fprint(w, "(fish) ") {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			line = last
+		}
+		last = line
+		if quit := d.command(w, line); quit {
+			fmt.Fprintln(w)
+			return nil
+		}
+	}
+	fmt.Fprintln(w)
+	return sc.Err()
+}
+
+// Script executes the commands read from r, one per line, as Serve does, but without prompting: each
+// command is echoed to w, followed by its results, so a debugging session can be automated or attached to a
+// bug report. Empty lines and lines starting with "#" are skipped. While it runs, the CodeBox's diagnostics,
+// including those written by the ><>, go to w. It stops after "quit", and only returns an error if r can't
+// be read.
+func (d *Debugger) Script(r io.Reader, w io.Writer) error {
+	diag := d.cB.diagnostics
+	d.cB.diagnostics = w
+	defer func() {
+		d.cB.diagnostics = diag
+	}()
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fmt.Fprintln(w, "(fish)", line)
+		if d.command(w, line) {
+			break
+		}
+	}
+	return sc.Err()
+}
+
+// command executes the debugger command line, writing its result to w. It returns true for "quit".
+func (d *Debugger) command(w io.Writer, line string) (quit bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+	args := strings.Join(fields[1:], " ")
+	switch fields[0] {
+	case "s", "step":
+		n := 1
+		if args != "" {
+			var err error
+			if n, err = strconv.Atoi(args); err != nil || n < 1 {
+				fmt.Fprintf(w, "Invalid step count %q\n", args)
+				return false
+			}
+		}
+		if d.stopped(w) {
+			return false
+		}
+		d.Step(n)
+		d.report(w)
+	case "c", "continue":
+		if d.stopped(w) {
+			return false
+		}
+		if _, err := d.Continue(); err == ErrMaxSteps {
+			fmt.Fprintf(w, "Paused after %d ticks without reaching a breakpoint\n", continueSteps)
+		}
+		d.report(w)
+	case "b", "break":
+		if args == "" {
+			for _, b := range d.Breakpoints() {
+				d.printBreak(w, b[0], b[1])
+			}
+			if len(d.breaks) == 0 {
+				fmt.Fprintln(w, "No breakpoints")
+			}
+			return false
+		}
+		cell, expr := args, ""
+		if i := strings.Index(args, " if "); i >= 0 {
+			cell, expr = args[:i], strings.TrimSpace(args[i+4:])
+		}
+		x, y, ok := parseCell(w, cell)
+		if !ok {
+			return false
+		}
+		if expr == "" {
+			d.Break(x, y)
+		} else if err := d.BreakIf(x, y, expr); err != nil {
+			fmt.Fprintln(w, "Invalid condition:", err)
+			return false
+		}
+		d.printBreak(w, x, y)
+	case "d", "delete":
+		if x, y, ok := parseCell(w, args); ok && !d.Delete(x, y) {
+			fmt.Fprintf(w, "No breakpoint at (%d,%d)\n", x, y)
+		}
+	case "p", "print":
+		d.printState(w)
+	case "box":
+		d.printBox()
+	case "m", "mark":
+		d.mark(w, fields[1:])
+	case "g", "goto":
+		d.goTo(w, args)
+	case "h", "help":
+		fmt.Fprintln(w, debuggerHelp)
+	case "q", "quit":
+		return true
+	default:
+		fmt.Fprintf(w, "Unknown command %q; try help\n", fields[0])
+	}
+	return false
+}
+
+// stopped reports whether the ><> can't move any more, telling w why.
+func (d *Debugger) stopped(w io.Writer) bool {
+	if d.done {
+		fmt.Fprintln(w, "The fish has halted")
+	} else if d.err != nil {
+		fmt.Fprintln(w, "The fish has failed:", d.err)
+	}
+	return d.done || d.err != nil
+}
+
+// report shows the codebox and state after the ><> has moved.
+func (d *Debugger) report(w io.Writer) {
+	if f, ok := d.err.(*Failure); ok {
+		d.cB.PrintFailure(f)
+		fmt.Fprintln(w, "The fish failed:", f)
+		return
+	}
+	d.printBox()
+	if d.done {
+		fmt.Fprintf(w, "The fish halted after %d steps\n", d.cB.Steps())
+		return
+	}
+	if x, y := d.cB.Position(); d.hit() {
+		d.printBreak(w, x, y)
+	}
+	d.printState(w)
+}
+
+// mark bookmarks a cell, as the "mark" command given args, or lists the bookmarks if there are no args.
+func (d *Debugger) mark(w io.Writer, args []string) {
+	if len(args) == 0 {
+		names := make([]string, 0, len(d.marks))
+		for name := range d.marks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s at (%d,%d)\n", name, d.marks[name][0], d.marks[name][1])
+		}
+		if len(names) == 0 {
+			fmt.Fprintln(w, "No bookmarks")
+		}
+		return
+	}
+	switch name := args[0]; {
+	case name == "output" || name == "write" || name == "fish" || strings.ContainsAny(name, ",0123456789"):
+		fmt.Fprintf(w, "Invalid bookmark name %q\n", name)
+	case len(args) == 1:
+		x, y := d.cB.Position()
+		d.Bookmark(name, x, y)
+		fmt.Fprintf(w, "%s at (%d,%d)\n", name, x, y)
+	default:
+		if x, y, ok := parseCell(w, strings.Join(args[1:], " ")); ok {
+			d.Bookmark(name, x, y)
+			fmt.Fprintf(w, "%s at (%d,%d)\n", name, x, y)
+		}
+	}
+}
+
+// goTo shows the codebox around where, as the "goto" command.
+func (d *Debugger) goTo(w io.Writer, where string) {
+	var cell *[2]int
+	switch where {
+	case "fish":
+		x, y := d.cB.Position()
+		cell = &[2]int{x, y}
+	case "output":
+		if cell = d.output; cell == nil {
+			fmt.Fprintln(w, "Nothing has been output")
+			return
+		}
+	case "write":
+		if cell = d.write; cell == nil {
+			fmt.Fprintln(w, "Nothing has been written with p")
+			return
+		}
+	default:
+		if c, ok := d.marks[where]; ok {
+			cell = &c
+		} else if x, y, ok := parseCell(w, where); ok {
+			cell = &[2]int{x, y}
+		} else {
+			return
+		}
+	}
+	if x, y := cell[0]+d.cB.ox, cell[1]+d.cB.oy; x < 0 || y < 0 || x >= d.cB.width || y >= d.cB.height {
+		fmt.Fprintf(w, "(%d,%d) is outside the codebox\n", cell[0], cell[1])
+		return
+	}
+	d.view = cell
+	d.printBox()
+}
+
+// printBox shows the codebox, or if it's too large, the part of it around the ><> or the cell shown by
+// goto, which is marked with brackets.
+func (d *Debugger) printBox() {
+	cB := d.cB
+	if d.view == nil && cB.width <= viewWidth && cB.height <= viewHeight {
+		cB.PrintBox()
+		return
+	}
+	cx, cy := cB.fX, cB.fY
+	if d.view != nil {
+		cx, cy = d.view[0]+cB.ox, d.view[1]+cB.oy
+	}
+	x0, y0 := viewStart(cx, cB.width, viewWidth), viewStart(cy, cB.height, viewHeight)
+	x1, y1 := x0+viewWidth, y0+viewHeight
+	if x1 > cB.width {
+		x1 = cB.width
+	}
+	if y1 > cB.height {
+		y1 = cB.height
+	}
+	w := cB.diag()
+	fmt.Fprintf(w, "\nShowing (%d,%d) to (%d,%d) of the codebox\n", x0-cB.ox, y0-cB.oy, x1-1-cB.ox, y1-1-cB.oy)
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			cell := string(cB.cell(x, y))
+			switch {
+			case x == cB.fX && y == cB.fY:
+				cell = "*" + cell + "*"
+			case d.view != nil && x == cx && y == cy:
+				cell = "[" + cell + "]"
+			default:
+				cell = " " + cell + " "
+			}
+			fmt.Fprint(w, cB.highlight(x, y, cell))
+		}
+		fmt.Fprintln(w)
+	}
+	cB.printLegend()
+}
+
+// viewStart returns the first of size cells to show so that as many as view are shown, centred on c where
+// possible.
+func viewStart(c, size, view int) int {
+	start := c - view/2
+	if start > size-view {
+		start = size - view
+	}
+	if start < 0 {
+		start = 0
+	}
+	return start
+}
+
+// printBreak shows the breakpoint at (x,y), and its condition.
+func (d *Debugger) printBreak(w io.Writer, x, y int) {
+	if b := d.breaks[[2]int{x, y}]; b.cond != nil {
+		fmt.Fprintf(w, "Breakpoint at (%d,%d) if %s\n", x, y, b.expr)
+	} else {
+		fmt.Fprintf(w, "Breakpoint at (%d,%d)\n", x, y)
+	}
+}
+
+// printState shows the position and direction of the ><>, and its stacks and register.
+func (d *Debugger) printState(w io.Writer) {
+	s := d.cB.Snapshot()
+	fmt.Fprintf(w, "Step %d at (%d,%d) swimming %v\n", s.Step, s.X, s.Y, s.Dir)
+	for i := len(s.Stacks) - 1; i >= 0; i-- {
+		fmt.Fprintf(w, "Stack %d: %s\n", i, formatStack(s.Stacks[i]))
+	}
+	if s.Register != nil {
+		fmt.Fprintln(w, "Register:", *s.Register)
+	} else {
+		fmt.Fprintln(w, "Register: empty")
+	}
+}
+
+// parseCell parses coordinates written as "x,y" or "x y", telling w if they're invalid.
+func parseCell(w io.Writer, s string) (x, y int, ok bool) {
+	f := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(f) == 2 {
+		var err1, err2 error
+		x, err1 = strconv.Atoi(f[0])
+		y, err2 = strconv.Atoi(f[1])
+		if err1 == nil && err2 == nil {
+			return x, y, true
+		}
+	}
+	fmt.Fprintf(w, "Invalid coordinates %q; expected x,y\n", s)
+	return 0, 0, false
+}
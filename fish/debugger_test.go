@@ -0,0 +1,146 @@
+package fish
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDebugger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	cB := NewCodeBox("12&3v\n;n+&<", nil, Spec, WithDiagnostics(buf), WithOutput(new(bytes.Buffer)))
+	d := NewDebugger(cB)
+	d.Break(3, 1)
+	if done, err := d.Continue(); done || err != nil {
+		t.Fatal(done, err)
+	}
+	if x, y := cB.Position(); x != 3 || y != 1 {
+		t.Errorf("stopped at (%d,%d), want the breakpoint at (3,1)", x, y)
+	}
+	if s := cB.Snapshot(); s.Register == nil || *s.Register != 2 {
+		t.Errorf("got register %v, want 2", s.Register)
+	}
+	if !d.Delete(3, 1) || d.Delete(3, 1) || len(d.Breakpoints()) != 0 {
+		t.Error("the breakpoint wasn't deleted")
+	}
+	if done, err := d.Continue(); !done || err != nil {
+		t.Fatal(done, err)
+	}
+	if done, _ := d.Step(1); !done || cB.Steps() != 10 {
+		t.Errorf("stepped after halting: done %v after %d steps", done, cB.Steps())
+	}
+}
+
+func TestDebuggerBreakIf(t *testing.T) {
+	// Counts down from 10 until 0
+	cB := NewCodeBox("a>1-:?!;10.", nil, Spec, WithDiagnostics(new(bytes.Buffer)))
+	d := NewDebugger(cB)
+	if err := d.BreakIf(2, 0, "stack[0] =="); err == nil {
+		t.Error("accepted an invalid condition")
+	}
+	if err := d.BreakIf(2, 0, "stack[0] == 3"); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := d.Continue(); done || err != nil {
+		t.Fatal(done, err)
+	}
+	if x, y := cB.Position(); x != 2 || y != 0 || formatStack(cB.Stack()) != "3" {
+		t.Errorf("stopped at (%d,%d) with stack %v", x, y, cB.Stack())
+	}
+	if done, err := d.Continue(); !done || err != nil {
+		t.Errorf("got %v, %v; the condition is false from now on", done, err)
+	}
+
+	cB = NewCodeBox(">", nil, Spec, WithDiagnostics(new(bytes.Buffer)))
+	d = NewDebugger(cB)
+	if done, err := d.Continue(); done || err != ErrMaxSteps || cB.Steps() != continueSteps {
+		t.Errorf("got %v, %v after %d steps", done, err, cB.Steps())
+	}
+	if done, _ := d.Step(1); done || cB.Steps() != continueSteps+1 {
+		t.Error("couldn't step after pausing")
+	}
+}
+
+func TestDebuggerNavigation(t *testing.T) {
+	buf := new(bytes.Buffer)
+	// Writes 1 to (10,0), and outputs from (5,0), in a codebox too wide to show at once
+	cB := NewCodeBox("1a0pao;"+strings.Repeat(" ", 53)+"x", nil, Spec, WithDiagnostics(buf),
+		WithOutput(new(bytes.Buffer)))
+	d := NewDebugger(cB)
+	if _, _, ok := d.LastOutput(); ok {
+		t.Error("got an output before running")
+	}
+	commands := "m start\nm end 60,0\nm 1x\nm\nc\ng output\ng write\ng end\ng 99,0\ng nowhere\nq\n"
+	if err := d.Serve(strings.NewReader(commands)); err != nil {
+		t.Fatal(err)
+	}
+	if x, y, ok := d.LastOutput(); !ok || x != 5 || y != 0 {
+		t.Errorf("got last output at (%d,%d)", x, y)
+	}
+	if x, y, ok := d.LastWrite(); !ok || x != 10 || y != 0 {
+		t.Errorf("got last write at (%d,%d)", x, y)
+	}
+	for _, want := range []string{
+		`Invalid bookmark name "1x"`,
+		"end at (60,0)\nstart at (0,0)\n",
+		"Showing (0,0) to (39,0) of the codebox\n 1  a  0  p  a [o]*;* ",
+		"Showing (0,0) to (39,0) of the codebox\n 1  a  0  p  a  o *;*         [\x01]",
+		"Showing (21,0) to (60,0) of the codebox\n",
+		"[x]\n",
+		"(99,0) is outside the codebox",
+		`Invalid coordinates "nowhere"`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output %q doesn't contain %q", buf, want)
+		}
+	}
+}
+
+func TestDebuggerServe(t *testing.T) {
+	buf := new(bytes.Buffer)
+	cB := NewCodeBox("1&2~~", nil, Spec, WithDiagnostics(buf))
+	commands := "b 3,0\nb 1,0 if depth >\nb 4,0 if reg == 1\nb\nc\np\n\ns 2\nbogus\nd 9 9\nb x\nq\nc\n"
+	if err := NewDebugger(cB).Serve(strings.NewReader(commands)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Invalid condition:",
+		"(fish) Breakpoint at (3,0)\nBreakpoint at (4,0) if reg == 1\n(fish) \n",
+		"Step 3 at (3,0) swimming Right\nStack 0: 2\nRegister: 1\n(fish) Step 3",
+		"Stack is empty!",
+		`Unknown command "bogus"`,
+		"No breakpoint at (9,9)",
+		`Invalid coordinates "x"`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output %q doesn't contain %q", buf, want)
+		}
+	}
+	if cB.Steps() != 5 {
+		t.Errorf("took %d steps, want 5; commands after quit shouldn't run", cB.Steps())
+	}
+}
+
+func TestDebuggerScript(t *testing.T) {
+	diag, out := new(bytes.Buffer), new(bytes.Buffer)
+	cB := NewCodeBox("1&2~~", nil, Spec, WithDiagnostics(diag))
+	commands := "# Reproduces the empty stack\nb 3,0\n\nc\np\nc\nq\nc\n"
+	if err := NewDebugger(cB).Script(strings.NewReader(commands), out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"(fish) b 3,0\nBreakpoint at (3,0)\n(fish) c\n",
+		"(fish) p\nStep 3 at (3,0) swimming Right\nStack 0: 2\nRegister: 1\n(fish) c\n",
+		"The fish failed: Stack is empty!",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output %q doesn't contain %q", out, want)
+		}
+	}
+	if strings.Contains(out.String(), "Reproduces") || strings.HasSuffix(out.String(), "(fish) c\n") {
+		t.Errorf("output %q has comments or commands after quit", out)
+	}
+	if diag.Len() != 0 {
+		t.Errorf("wrote %q to the diagnostics", diag)
+	}
+}
@@ -0,0 +1,74 @@
+package fish
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lookalikes maps characters which are commonly mistaken for instructions to those instructions.
+var lookalikes = map[byte]string{
+	'`':  "'",
+	'O':  "o0",
+	'I':  "i|l",
+	'L':  "l|",
+	'\t': " ",
+}
+
+// isInstruction returns true if r is a built-in instruction, or is provided by an enabled extension.
+func (cB *CodeBox) isInstruction(r byte) bool {
+	_, builtin := descriptions[r]
+	_, ext := cB.ext[r]
+	return builtin || ext || r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || strings.IndexByte("|_#/\\", r) != -1
+}
+
+// suggestions returns the instructions which r was most likely meant to be.
+func (cB *CodeBox) suggestions(r byte) (s []byte) {
+	for _, c := range []byte(lookalikes[r]) {
+		if cB.isInstruction(c) {
+			s = append(s, c)
+		}
+	}
+	for _, c := range []byte{r | 0x20, r &^ 0x20} {
+		if c != r && (r|0x20 >= 'a' && r|0x20 <= 'z') && cB.isInstruction(c) && strings.IndexByte(string(s), c) == -1 {
+			s = append(s, c)
+		}
+	}
+	return
+}
+
+// unclosedQuote returns the quote which appears an odd number of times on the line the ><> is swimming
+// along, or 0 if there is no such quote. A lone quote wraps around the line and closes itself, after
+// which the rest of the intended string is executed as instructions.
+func (cB *CodeBox) unclosedQuote() byte {
+	var line []byte
+	if cB.fDir == Right || cB.fDir == Left {
+		line = cB.box[cB.fY]
+	} else {
+		for _, row := range cB.box {
+			line = append(line, row[cB.fX])
+		}
+	}
+	for _, q := range []byte{'"', '\''} {
+		if strings.Count(string(line), string(q))%2 == 1 {
+			return q
+		}
+	}
+	return 0
+}
+
+// diagnose returns a description of the invalid instruction r under the ><>, with suggestions for what
+// might have been meant.
+func (cB *CodeBox) diagnose(r byte) string {
+	msg := fmt.Sprintf("invalid instruction %q at (%d,%d)", r, cB.fX, cB.fY)
+	if s := cB.suggestions(r); len(s) > 0 {
+		quoted := make([]string, len(s))
+		for i, c := range s {
+			quoted[i] = fmt.Sprintf("%q", c)
+		}
+		msg += "; did you mean " + strings.Join(quoted, " or ") + "?"
+	}
+	if q := cB.unclosedQuote(); q != 0 {
+		msg += fmt.Sprintf("\nthis line has an unmatched %q; was a string never closed?", q)
+	}
+	return msg
+}
@@ -0,0 +1,21 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	cB := NewCodeBox("N;", []float64{}, Spec)
+	if got, want := cB.diagnose('N'), `invalid instruction 'N' at (0,0); did you mean 'n'?`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	cB = NewCodeBox(`"Hello, world;`, []float64{}, Spec)
+	for i := 0; i < 15; i++ {
+		cB.Swim()
+	}
+	want := "invalid instruction 'H' at (1,0)\nthis line has an unmatched '\"'; was a string never closed?"
+	if got := cB.diagnose(cB.box[cB.fY][cB.fX]); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,110 @@
+package fish
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+// Divergence describes the first step at which a script behaves differently in two runs, such as in two
+// Modes, which shows the interpreter quirks the script depends on.
+type Divergence struct {
+	Step   int  // Steps executed before the one which diverged
+	X, Y   int  // Position of the instruction which diverged
+	Op     rune // The instruction which diverged
+	Reason string
+}
+
+func (d *Divergence) String() string {
+	return fmt.Sprintf("step %d, %q at (%d,%d): %s", d.Step, d.Op, d.X, d.Y, d.Reason)
+}
+
+// Diverge runs script in the Modes a and b side by side, deterministically, for at most maxSteps steps, and
+// returns the first step at which they differ. It returns nil if they don't.
+func Diverge(script string, a, b Mode, stack []float64, input []byte, maxSteps int) *Divergence {
+	var boxes [2]*CodeBox
+	var logs [2]EventLog
+	modes := [2]Mode{a, b}
+	for i := range boxes {
+		boxes[i] = NewCodeBox(script, append([]float64{}, stack...), modes[i], WithDeterministic(),
+			WithInput(bytes.NewReader(input)), WithOutput(ioutil.Discard), WithEventLog(&logs[i]))
+	}
+	for step := 0; step < maxSteps; step++ {
+		x, y := boxes[0].fX, boxes[0].fY
+		op := boxes[0].cell(x, y)
+		var done [2]bool
+		var fails [2]string
+		for i, cB := range boxes {
+			done[i], fails[i] = swimRecover(cB)
+		}
+		if reason := diverged(modes, boxes, logs, done, fails); reason != "" {
+			return &Divergence{step, x, y, op, reason}
+		} else if done[0] || fails[0] != "" {
+			break
+		}
+	}
+	return nil
+}
+
+// swimRecover is swim which returns a description of any failure, rather than panicking.
+func swimRecover(cB *CodeBox) (done bool, fail string) {
+	defer func() {
+		if r := recover(); r != nil {
+			fail = failure(r)
+		}
+	}()
+	return cB.swim(), ""
+}
+
+// diverged returns a description of how the boxes running in modes differ, or "" if they don't.
+func diverged(modes [2]Mode, boxes [2]*CodeBox, logs [2]EventLog, done [2]bool, fails [2]string) string {
+	n, c := boxes[0], boxes[1]
+	a, b := modes[0], modes[1]
+	switch {
+	case fails[0] != fails[1]:
+		return fmt.Sprintf("%v mode failed with %q, %v mode with %q", a, fails[0], b, fails[1])
+	case done[0] != done[1]:
+		return "only one mode halted"
+	case n.fX != c.fX || n.fY != c.fY:
+		return fmt.Sprintf("%v mode moved to (%d,%d), %v mode to (%d,%d)", a, n.fX, n.fY, b, c.fX, c.fY)
+	case n.fDir != c.fDir:
+		return fmt.Sprintf("%v mode is moving %v, %v mode %v", a, n.fDir, b, c.fDir)
+	case len(n.stacks) != len(c.stacks) || n.p != c.p:
+		return "the modes have different numbers of stacks"
+	case len(logs[0].Events) != len(logs[1].Events):
+		return "only one mode performed I/O"
+	}
+	if len(logs[0].Events) > 0 {
+		if e, e2 := logs[0].Events[len(logs[0].Events)-1], logs[1].Events[len(logs[1].Events)-1]; e != e2 {
+			return fmt.Sprintf("%v mode performed %v %v, %v mode %v %v", a, e.Kind, e.Value, b, e2.Kind, e2.Value)
+		}
+	}
+	for i := range n.stacks {
+		s, s2 := n.stacks[i], c.stacks[i]
+		if !sameValues(s.S, s2.S) {
+			return fmt.Sprintf("stack %d is %v in %v mode, %v in %v mode", i, s.S, a, s2.S, b)
+		} else if s.filledRegister != s2.filledRegister || s.filledRegister && s.register != s2.register {
+			return fmt.Sprintf("the register of stack %d differs", i)
+		}
+	}
+	for y := range n.box {
+		if !bytes.Equal(n.box[y], c.box[y]) {
+			return fmt.Sprintf("row %d of the codebox differs", y)
+		}
+	}
+	return ""
+}
+
+// sameValues returns true if s and s2 hold the same values, treating NaNs as equal.
+func sameValues(s, s2 []float64) bool {
+	if len(s) != len(s2) {
+		return false
+	}
+	for i := range s {
+		if math.Float64bits(s[i]) != math.Float64bits(s2[i]) {
+			return false
+		}
+	}
+	return true
+}
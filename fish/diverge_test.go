@@ -0,0 +1,33 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestDiverge(t *testing.T) {
+	if d := Diverge("12+n;", Spec, FishLanguageCom, nil, nil, 100); d != nil {
+		t.Error(d)
+	}
+
+	d := Diverge("1232[]~n;", Spec, FishLanguageCom, nil, nil, 100)
+	if d == nil || d.Step != 4 || d.Op != '[' || d.X != 4 {
+		t.Fatal(d)
+	}
+	if want := "step 4, '[' at (4,0): stack 1 is [2 3] in spec mode, [3 2] in fishlanguage.com mode"; d.String() != want {
+		t.Errorf("got %q, want %q", d.String(), want)
+	}
+
+	d = Diverge("01-0g;", Spec, FishLanguageCom, nil, nil, 100)
+	if d == nil || d.Op != 'g' {
+		t.Fatal(d)
+	}
+
+	// The websites differ in "[", "]" and "%"
+	if d := Diverge("1232[]~n;", FishLanguageCom, FishInterpreterCom, nil, nil, 100); d == nil ||
+		d.Reason != "stack 1 is [3 2] in fishlanguage.com mode, [2 3] in fishinterpreter.com mode" {
+		t.Error(d)
+	}
+	if d := Diverge("01-2%n;", FishLanguageCom, FishInterpreterCom, nil, nil, 100); d == nil || d.Op != '%' {
+		t.Error(d)
+	}
+}
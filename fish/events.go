@@ -0,0 +1,85 @@
+package fish
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// EventKind is the kind of an I/O Event.
+type EventKind byte
+
+const (
+	OutputChar EventKind = iota // "o"
+	OutputNum                   // "n"
+	InputByte                   // "i", with a Value of -1 if no input was available
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case OutputChar:
+		return "OutputChar"
+	case OutputNum:
+		return "OutputNum"
+	case InputByte:
+		return "InputByte"
+	}
+	return "Unknown"
+}
+
+// Event is a single I/O operation performed by a ><>.
+type Event struct {
+	Kind  EventKind
+	Value float64
+}
+
+// EventLog records the I/O a ><> performs, in order. Attach one to a CodeBox with WithEventLog.
+type EventLog struct {
+	Events []Event
+	Runes  bool // Whether the ><> was laid out WithRunes, so "o" wrote any character rather than a byte
+}
+
+// WithEventLog records the ><>'s I/O in l.
+func WithEventLog(l *EventLog) Option {
+	return func(cB *CodeBox) {
+		cB.events = l
+	}
+}
+
+// record appends an Event to the CodeBox's EventLog, if it has one.
+func (cB *CodeBox) record(kind EventKind, v float64) {
+	if cB.events != nil {
+		cB.events.Events = append(cB.events.Events, Event{kind, v})
+		cB.events.Runes = cB.runes
+	}
+}
+
+// Output returns the output recorded in l, as it was written.
+func (l *EventLog) Output() string {
+	buf := new(bytes.Buffer)
+	for _, e := range l.Events {
+		switch e.Kind {
+		case OutputChar:
+			if l.Runes {
+				buf.WriteRune(rune(e.Value))
+			} else {
+				buf.WriteRune(rune(byte(e.Value)))
+			}
+		case OutputNum:
+			fmt.Fprintf(buf, "%v", e.Value)
+		}
+	}
+	return buf.String()
+}
+
+// Replay makes "i" return exactly the values recorded in l, including any reads which found no input, so
+// a run can be reproduced. Once they are exhausted, "i" pushes -1.
+func (l *EventLog) Replay() Option {
+	return func(cB *CodeBox) {
+		cB.replay = []float64{}
+		for _, e := range l.Events {
+			if e.Kind == InputByte {
+				cB.replay = append(cB.replay, e.Value)
+			}
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package fish
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEventLog(t *testing.T) {
+	log := new(EventLog)
+	cB := NewCodeBox("ii+n'!'oi;", []float64{}, Spec, WithInput(strings.NewReader("\x01\x02")), WithEventLog(log))
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	want := []Event{{InputByte, 1}, {InputByte, 2}, {OutputNum, 3}, {OutputChar, '!'}, {InputByte, -1}}
+	if len(log.Events) != len(want) {
+		t.Fatal(log.Events)
+	}
+	for i := range want {
+		if log.Events[i] != want[i] {
+			t.Errorf("event %d = %v, want %v", i, log.Events[i], want[i])
+		}
+	}
+	if log.Output() != "3!" {
+		t.Error(log.Output())
+	}
+
+	replayed := new(EventLog)
+	cB = NewCodeBox("ii+n'!'oi;", []float64{}, Spec, log.Replay(), WithEventLog(replayed))
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed.Events) != len(want) || replayed.Output() != "3!" || cB.Pop() != -1 {
+		t.Fail()
+	}
+}
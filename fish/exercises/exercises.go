@@ -0,0 +1,111 @@
+// Package exercises runs ><> courses. An Exercise bundles a prompt and a starter codebox, which are shown to
+// students, with hidden test cases, which a student's program is checked against one by one.
+package exercises
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/redstarcoder/go-fish/fish"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Exercise is a task for students, usually read from JSON with Read:
+//
+//	{
+//		"name": "Double",
+//		"prompt": "Output twice the number on the stack.",
+//		"starter": "n;",
+//		"maxSteps": 100,
+//		"cases": [{"stack": [2], "output": "4"}, {"stack": [5], "output": "10"}]
+//	}
+type Exercise struct {
+	Name     string      `json:"name"`
+	Prompt   string      `json:"prompt"`
+	Starter  string      `json:"starter"`  // The codebox students begin from
+	MaxSteps int         `json:"maxSteps"` // Per case; programs which don't halt in time fail it
+	Cases    []fish.Case `json:"cases"`    // Hidden from students
+}
+
+// defaultMaxSteps stops programs which never halt when an Exercise doesn't set MaxSteps.
+const defaultMaxSteps = 1000000
+
+// Read reads a JSON Exercise from r.
+func Read(r io.Reader) (*Exercise, error) {
+	e := new(Exercise)
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(e); err != nil {
+		return nil, fmt.Errorf("exercise: %v", err)
+	}
+	return e, nil
+}
+
+// Public returns a copy of the Exercise without its cases, which is safe to show to students.
+func (e *Exercise) Public() *Exercise {
+	p := *e
+	p.Cases = nil
+	return &p
+}
+
+// Result is the outcome of one case of an Exercise.
+type Result struct {
+	Case   int // Counting from 1
+	Passed bool
+	Steps  int
+	Reason string // Why the case failed, without revealing its input or expected output
+}
+
+func (r Result) String() string {
+	if r.Passed {
+		return fmt.Sprintf("case %d: passed in %d steps", r.Case, r.Steps)
+	}
+	return fmt.Sprintf("case %d: failed: %s", r.Case, r.Reason)
+}
+
+// Check runs script against every case of the Exercise, each for at most MaxSteps steps, and returns a
+// Result for each. It only returns an error if script can't be run at all.
+func (e *Exercise) Check(script string) ([]Result, error) {
+	p, err := fish.Compile(script, fish.Spec)
+	if err != nil {
+		return nil, err
+	}
+	maxSteps := e.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = defaultMaxSteps
+	}
+	results := make([]Result, len(e.Cases))
+	for i, tc := range e.Cases {
+		out := new(bytes.Buffer)
+		cB := p.New(append([]float64(nil), tc.Stack...), fish.WithInput(strings.NewReader(tc.Input)),
+			fish.WithOutput(out), fish.WithDiagnostics(ioutil.Discard), fish.WithDeterministic())
+		r := Result{Case: i + 1}
+		r.Steps, err = cB.Run(maxSteps)
+		switch {
+		case err == fish.ErrMaxSteps:
+			r.Reason = fmt.Sprintf("did not halt within %d steps", maxSteps)
+		case err != nil:
+			r.Reason = err.Error()
+		case out.String() != tc.Output:
+			r.Reason = "wrong output"
+		default:
+			r.Passed = true
+		}
+		cB.Close()
+		results[i] = r
+	}
+	return results, nil
+}
+
+// Passed returns how many of results passed.
+func Passed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Passed {
+			n++
+		}
+	}
+	return n
+}
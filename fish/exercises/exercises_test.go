@@ -0,0 +1,59 @@
+package exercises
+
+import (
+	"strings"
+	"testing"
+)
+
+const testExercise = `{
+	"name": "Double",
+	"prompt": "Output twice the number on the stack.",
+	"starter": "n;",
+	"maxSteps": 50,
+	"cases": [{"stack": [2], "output": "4"}, {"stack": [5], "output": "10"}, {"stack": [0], "output": "0"}]
+}`
+
+func TestCheck(t *testing.T) {
+	e, err := Read(strings.NewReader(testExercise))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := e.Public(); p.Cases != nil || p.Prompt != e.Prompt || len(e.Cases) != 3 {
+		t.Errorf("got %+v from %+v", p, e)
+	}
+
+	for _, test := range []struct {
+		script string
+		want   []string
+	}{
+		{"2*n;", []string{"case 1: passed in 4 steps", "case 2: passed in 4 steps", "case 3: passed in 4 steps"}},
+		{"n;", []string{"case 1: failed: wrong output", "case 2: failed: wrong output",
+			"case 3: passed in 2 steps"}},
+		{":?!;2*n;", []string{"case 1: passed in 7 steps", "case 2: passed in 7 steps",
+			"case 3: failed: wrong output"}},
+		{":?!v2*n;\n   >", []string{"case 1: passed in 7 steps", "case 2: passed in 7 steps",
+			"case 3: failed: did not halt within 50 steps"}},
+		{"~~", []string{"case 1: failed: Stack is empty! ('~' at 1,0)",
+			"case 2: failed: Stack is empty! ('~' at 1,0)", "case 3: failed: Stack is empty! ('~' at 1,0)"}},
+	} {
+		results, err := e.Check(test.script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, r := range results {
+			if r.String() != test.want[i] {
+				t.Errorf("%q: got %q, want %q", test.script, r, test.want[i])
+			}
+		}
+	}
+
+	if results, _ := e.Check("2*n;"); Passed(results) != 3 {
+		t.Errorf("got %d passed", Passed(results))
+	}
+	if _, err := e.Check(""); err == nil {
+		t.Error("checked an empty script")
+	}
+	if _, err := Read(strings.NewReader(`{"cases": [{"ouptut": "1"}]}`)); err == nil {
+		t.Error("unknown fields should be rejected")
+	}
+}
@@ -0,0 +1,107 @@
+package fish
+
+import (
+	"fmt"
+)
+
+// descriptions holds a short description of every ><> instruction.
+var descriptions = map[byte]string{
+	' ':  "does nothing",
+	';':  "ends the program",
+	'>':  "makes you swim Right",
+	'v':  "makes you swim Down",
+	'<':  "makes you swim Left",
+	'^':  "makes you swim Up",
+	'x':  "makes you swim in a random direction",
+	'"':  "toggles string mode",
+	'\'': "toggles string mode",
+	'&':  "moves a value between the stack and the register",
+	'o':  "pops a value and outputs it as a character",
+	'n':  "pops a value and outputs it as a number",
+	'r':  "reverses the stack",
+	'+':  "pops x and y and pushes y+x",
+	'-':  "pops x and y and pushes y-x",
+	'*':  "pops x and y and pushes y*x",
+	',':  "pops x and y and pushes y/x",
+	'%':  "pops x and y and pushes y%x",
+	'=':  "pops x and y and pushes 1 if y=x, otherwise 0",
+	')':  "pops x and y and pushes 1 if y>x, otherwise 0",
+	'(':  "pops x and y and pushes 1 if y<x, otherwise 0",
+	'!':  "skips the next instruction",
+	'?':  "pops a value and skips the next instruction if it is 0",
+	'.':  "pops y and x and jumps to (x,y)",
+	':':  "duplicates the top value",
+	'~':  "pops and discards the top value",
+	'$':  "swaps the top two values",
+	'@':  "moves the top value back two places",
+	'}':  "shifts the stack right",
+	'{':  "shifts the stack left",
+	']':  "closes the current stack, moving its values onto the one below",
+	'[':  "pops n and moves the top n values onto a new stack",
+	'l':  "pushes the length of the stack",
+	'g':  "pops y and x and pushes the value of the cell at (x,y), or 0 if it's outside the codebox",
+	'p':  "pops y, x and v, and writes v into the cell at (x,y), growing the codebox if needed",
+	'i':  "reads a byte of input and pushes it, or -1 if there is none",
+}
+
+// pops holds the number of values popped by instructions which pop a fixed number of values.
+var pops = map[byte]int{
+	'o': 1, 'n': 1, '?': 1, '~': 1, ':': 1, '[': 1,
+	'+': 2, '-': 2, '*': 2, ',': 2, '%': 2, '=': 2, ')': 2, '(': 2, '.': 2, '$': 2, 'g': 2,
+	'@': 3, 'p': 3,
+}
+
+// Explain returns a human-readable description of what the instruction at (x,y) will do, given the
+// current direction and stack.
+func (cB *CodeBox) Explain(x, y int) string {
+	if x < 0 || y < 0 || x >= cB.width || y >= cB.height {
+		return fmt.Sprintf("(%d,%d) is outside the codebox", x, y)
+	}
+	r := cB.box[y][x]
+	if cB.stringMode != 0 && r != cB.stringMode {
+		return fmt.Sprintf("%q: in string mode, pushes %d", r, r)
+	}
+	s := cB.Stack()
+	if n := pops[r]; n > len(s) {
+		return fmt.Sprintf("%q: %s, but the stack only holds %d values; something will smell fishy", r,
+			descriptions[r], len(s))
+	}
+	switch r {
+	case '|', '_', '#', '/', '\\':
+		if d := cB.fDir.mirror(r); d != cB.fDir {
+			return fmt.Sprintf("%q: moving %v, you will be deflected %v", r, cB.fDir, d)
+		}
+		return fmt.Sprintf("%q: moving %v, you will pass through", r, cB.fDir)
+	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return fmt.Sprintf("%q: pushes %d", r, r-'0')
+	case 'a', 'b', 'c', 'd', 'e', 'f':
+		return fmt.Sprintf("%q: pushes %d", r, r-'a'+10)
+	case '+', '-', '*', ',', '%', '=', ')', '(':
+		return fmt.Sprintf("%q: %s, with x=%v and y=%v", r, descriptions[r], s[len(s)-1], s[len(s)-2])
+	case '?':
+		if s[len(s)-1] == 0 {
+			return fmt.Sprintf("%q: pops 0, so the next instruction will be skipped", r)
+		}
+		return fmt.Sprintf("%q: pops %v, so the next instruction will be executed", r, s[len(s)-1])
+	case '.':
+		return fmt.Sprintf("%q: jumps to (%v,%v)", r, s[len(s)-2], s[len(s)-1])
+	case '&':
+		if cB.stacks[cB.p].filledRegister {
+			return fmt.Sprintf("%q: pushes %v from the register", r, cB.stacks[cB.p].register)
+		} else if len(s) == 0 {
+			return fmt.Sprintf("%q: the register is empty and so is the stack; something will smell fishy", r)
+		}
+		return fmt.Sprintf("%q: pops %v into the register", r, s[len(s)-1])
+	case '"', '\'':
+		if cB.stringMode == r {
+			return fmt.Sprintf("%q: leaves string mode", r)
+		}
+		return fmt.Sprintf("%q: enters string mode until the next %q", r, r)
+	}
+	if d, ok := descriptions[r]; ok {
+		return fmt.Sprintf("%q: %s", r, d)
+	} else if _, ok := cB.ext[r]; ok {
+		return fmt.Sprintf("%q: executes an extension instruction", r)
+	}
+	return fmt.Sprintf("%q: is not an instruction; something will smell fishy", r)
+}
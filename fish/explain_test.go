@@ -0,0 +1,25 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	cB := NewCodeBox(`\+?q"`, []float64{TESTVALUE1, TESTVALUE2}, Spec)
+	tests := []struct {
+		x, y int
+		want string
+	}{
+		{0, 0, `'\\': moving Right, you will be deflected Down`},
+		{1, 0, `'+': pops x and y and pushes y+x, with x=2 and y=1`},
+		{2, 0, `'?': pops 2, so the next instruction will be executed`},
+		{3, 0, `'q': is not an instruction; something will smell fishy`},
+		{4, 0, `'"': enters string mode until the next '"'`},
+		{5, 0, `(5,0) is outside the codebox`},
+	}
+	for _, test := range tests {
+		if got := cB.Explain(test.x, test.y); got != test.want {
+			t.Errorf("Explain(%d, %d) = %q, want %q", test.x, test.y, got, test.want)
+		}
+	}
+}
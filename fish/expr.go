@@ -0,0 +1,226 @@
+package fish
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseExpr parses a watch expression into a Probe, so watches, challenge assertions and other tools can
+// share one way of describing a ><>'s state. An expression is made of numbers, the operators
+//
+//	||  &&  ==  !=  <  <=  >  >=  +  -  *  /  %  !  ( )
+//
+// and the names
+//
+//	depth      the length of the current stack
+//	stack[n]   the n'th value of the current stack, counting from 0 at the top
+//	reg        the register of the current stack
+//	filled     1 if the register is filled, otherwise 0
+//	cell(x,y)  the value of the codebox at (x,y)
+//	x, y       the ><>'s position
+//	dir        the ><>'s direction: 0 for right, 1 down, 2 left and 3 up
+//	steps      the number of steps executed
+//
+// Comparisons and logical operators yield 1 or 0, and values which don't exist, such as stack[3] of a
+// shorter stack or a cell outside the codebox, are NaN.
+func ParseExpr(s string) (Probe, error) {
+	p := &exprParser{s: s}
+	p.next()
+	e := p.or()
+	if p.err == nil && p.tok != "" {
+		p.fail("unexpected %q", p.tok)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return e, nil
+}
+
+// exprParser is a recursive descent parser for ParseExpr. tok is the current token, or "" at the end.
+type exprParser struct {
+	s   string
+	pos int
+	tok string
+	err error
+}
+
+func (p *exprParser) fail(format string, a ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf("expression %q: %s", p.s, fmt.Sprintf(format, a...))
+	}
+}
+
+func (p *exprParser) next() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+	start := p.pos
+	switch {
+	case p.pos == len(p.s):
+	case unicode.IsDigit(rune(p.s[p.pos])) || p.s[p.pos] == '.':
+		for p.pos < len(p.s) && (unicode.IsDigit(rune(p.s[p.pos])) || p.s[p.pos] == '.') {
+			p.pos++
+		}
+	case unicode.IsLetter(rune(p.s[p.pos])):
+		for p.pos < len(p.s) && unicode.IsLetter(rune(p.s[p.pos])) {
+			p.pos++
+		}
+	default:
+		p.pos++
+		for _, op := range []string{"||", "&&", "==", "!=", "<=", ">="} {
+			if strings.HasPrefix(p.s[start:], op) {
+				p.pos = start + 2
+			}
+		}
+	}
+	p.tok = p.s[start:p.pos]
+}
+
+func (p *exprParser) expect(tok string) {
+	if p.tok != tok {
+		p.fail("expected %q", tok)
+	}
+	p.next()
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// binary parses a left-associative sequence of operands separated by the given operators.
+func (p *exprParser) binary(operand func() Probe, ops map[string]func(a, b float64) float64) Probe {
+	e := operand()
+	for f, ok := ops[p.tok]; ok && p.err == nil; f, ok = ops[p.tok] {
+		p.next()
+		l, r := e, operand()
+		e = func(cB *CodeBox) float64 {
+			return f(l(cB), r(cB))
+		}
+	}
+	return e
+}
+
+func (p *exprParser) or() Probe {
+	return p.binary(p.and, map[string]func(a, b float64) float64{
+		"||": func(a, b float64) float64 { return truth(a != 0 || b != 0) },
+	})
+}
+
+func (p *exprParser) and() Probe {
+	return p.binary(p.comparison, map[string]func(a, b float64) float64{
+		"&&": func(a, b float64) float64 { return truth(a != 0 && b != 0) },
+	})
+}
+
+func (p *exprParser) comparison() Probe {
+	return p.binary(p.sum, map[string]func(a, b float64) float64{
+		"==": func(a, b float64) float64 { return truth(a == b) },
+		"!=": func(a, b float64) float64 { return truth(a != b) },
+		"<":  func(a, b float64) float64 { return truth(a < b) },
+		"<=": func(a, b float64) float64 { return truth(a <= b) },
+		">":  func(a, b float64) float64 { return truth(a > b) },
+		">=": func(a, b float64) float64 { return truth(a >= b) },
+	})
+}
+
+func (p *exprParser) sum() Probe {
+	return p.binary(p.product, map[string]func(a, b float64) float64{
+		"+": func(a, b float64) float64 { return a + b },
+		"-": func(a, b float64) float64 { return a - b },
+	})
+}
+
+func (p *exprParser) product() Probe {
+	return p.binary(p.unary, map[string]func(a, b float64) float64{
+		"*": func(a, b float64) float64 { return a * b },
+		"/": func(a, b float64) float64 { return a / b },
+		"%": math.Mod,
+	})
+}
+
+func (p *exprParser) unary() Probe {
+	switch p.tok {
+	case "-":
+		p.next()
+		e := p.unary()
+		return func(cB *CodeBox) float64 { return -e(cB) }
+	case "!":
+		p.next()
+		e := p.unary()
+		return func(cB *CodeBox) float64 { return truth(e(cB) == 0) }
+	}
+	return p.operand()
+}
+
+func (p *exprParser) operand() Probe {
+	tok := p.tok
+	p.next()
+	switch tok {
+	case "(":
+		e := p.or()
+		p.expect(")")
+		return e
+	case "depth":
+		return StackDepth
+	case "filled":
+		return RegisterFilled
+	case "reg":
+		return func(cB *CodeBox) float64 {
+			if s := cB.stacks[cB.p]; s.filledRegister {
+				return s.register
+			}
+			return math.NaN()
+		}
+	case "x":
+		return func(cB *CodeBox) float64 { return float64(cB.fX - cB.ox) }
+	case "y":
+		return func(cB *CodeBox) float64 { return float64(cB.fY - cB.oy) }
+	case "dir":
+		return func(cB *CodeBox) float64 { return float64(cB.fDir) }
+	case "steps":
+		return func(cB *CodeBox) float64 { return float64(cB.steps) }
+	case "stack":
+		p.expect("[")
+		i := p.or()
+		p.expect("]")
+		return func(cB *CodeBox) float64 {
+			s, n := cB.Stack(), i(cB)
+			if n != math.Trunc(n) || n < 0 || n >= float64(len(s)) {
+				return math.NaN()
+			}
+			return s[len(s)-1-int(n)]
+		}
+	case "cell":
+		p.expect("(")
+		x := p.or()
+		p.expect(",")
+		y := p.or()
+		p.expect(")")
+		return func(cB *CodeBox) float64 {
+			x, y := x(cB), y(cB)
+			if x != math.Trunc(x) || y != math.Trunc(y) {
+				return math.NaN()
+			}
+			bx, by := int(x)+cB.ox, int(y)+cB.oy
+			if bx < 0 || by < 0 || bx >= cB.width || by >= cB.height {
+				return math.NaN()
+			}
+			return float64(cB.cell(bx, by))
+		}
+	}
+	if v, err := strconv.ParseFloat(tok, 64); err == nil {
+		return func(cB *CodeBox) float64 { return v }
+	}
+	if tok == "" {
+		p.fail("unexpected end")
+	} else {
+		p.fail("unexpected %q", tok)
+	}
+	return func(cB *CodeBox) float64 { return math.NaN() }
+}
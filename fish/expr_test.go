@@ -0,0 +1,85 @@
+package fish
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseExpr(t *testing.T) {
+	cB := NewCodeBox("123&v\n    ;", nil, Spec)
+	for i := 0; i < 5; i++ {
+		cB.swim()
+	}
+	for expr, want := range map[string]float64{
+		"depth":                   2,
+		"stack[0]":                2,
+		"stack[depth-1]":          1,
+		"stack[2]":                math.NaN(),
+		"reg":                     3,
+		"filled && reg == 3":      1,
+		"x":                       4,
+		"y == 1 || 0":             1,
+		"dir":                     1,
+		"steps":                   5,
+		"cell(3, 0)":              '&',
+		"cell(9, 9)":              math.NaN(),
+		"1 + 2 * 3 - (4 - 1) / 3": 6,
+		"-stack[0] % 3":           -2,
+		"!depth":                  0,
+		"2 <= 2 && 3 > 2 && 1 != 2 && !(1 >= 2) && 1 < 2": 1,
+		"1.5 * 2": 3,
+	} {
+		p, err := ParseExpr(expr)
+		if err != nil {
+			t.Errorf("%q: %v", expr, err)
+			continue
+		}
+		if got := p(cB); got != want && !(math.IsNaN(got) && math.IsNaN(want)) {
+			t.Errorf("%q: got %v, want %v", expr, got, want)
+		}
+	}
+
+	for expr, want := range map[string]string{
+		"":          `expression "": unexpected end`,
+		"1 +":       `expression "1 +": unexpected end`,
+		"stack[0":   `expression "stack[0": expected "]"`,
+		"depth 2":   `expression "depth 2": unexpected "2"`,
+		"register":  `expression "register": unexpected "register"`,
+		"cell(1 2)": `expression "cell(1 2)": expected ","`,
+	} {
+		if _, err := ParseExpr(expr); err == nil || err.Error() != want {
+			t.Errorf("%q: got %v, want %q", expr, err, want)
+		}
+	}
+}
+
+func TestAssert(t *testing.T) {
+	c := &Challenge{Assert: []string{"depth == 0"}, Cases: []Case{{Output: "3"}}}
+	if _, err := c.Check("12+n;"); err != nil {
+		t.Error(err)
+	}
+	if _, err := c.Check("12+:n;"); err == nil || err.Error() != `case 1: assertion "depth == 0" failed` {
+		t.Error(err)
+	}
+	c.Assert = []string{"depth =="}
+	if _, err := c.Check("12+n;"); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestAddExpr(t *testing.T) {
+	w := NewWatch(1)
+	s, err := w.AddExpr("depth * 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.AddExpr("depth *"); err == nil {
+		t.Error("expected a parse error")
+	}
+	cB := NewCodeBox("12;", nil, Spec, WithWatch(w))
+	for !cB.swim() {
+	}
+	if s.Name != "depth * 2" || !sameValues(s.Values, []float64{0, 2, 4}) {
+		t.Errorf("%+v", s)
+	}
+}
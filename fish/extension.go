@@ -0,0 +1,23 @@
+package fish
+
+// Instruction is the implementation of a single extension instruction. Like the built-in instructions, it
+// should panic if it cannot be executed.
+type Instruction func(cB *CodeBox)
+
+// Extension is a set of instructions which aren't part of ><>, and must be enabled with WithExtension.
+type Extension interface {
+	// Instructions returns the instructions provided by the extension, keyed by opcode.
+	Instructions() map[byte]Instruction
+}
+
+// WithExtension enables the instructions provided by ext. Built-in instructions cannot be overridden.
+func WithExtension(ext Extension) Option {
+	return func(cB *CodeBox) {
+		if cB.ext == nil {
+			cB.ext = make(map[byte]Instruction)
+		}
+		for op, f := range ext.Instructions() {
+			cB.ext[op] = f
+		}
+	}
+}
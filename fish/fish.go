@@ -1,7 +1,10 @@
 package fish
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"strings"
@@ -18,7 +21,72 @@ const (
 	Up
 )
 
-var reader chan byte
+// String returns the name of d.
+func (d Direction) String() string {
+	switch d {
+	case Right:
+		return "Right"
+	case Down:
+		return "Down"
+	case Left:
+		return "Left"
+	case Up:
+		return "Up"
+	}
+	return "Unknown"
+}
+
+// mirror returns the direction a ><> swimming in d will be swimming after passing through the mirror r.
+func (d Direction) mirror(r byte) Direction {
+	switch r {
+	case '|':
+		if d == Right {
+			return Left
+		} else if d == Left {
+			return Right
+		}
+	case '_':
+		if d == Down {
+			return Up
+		} else if d == Up {
+			return Down
+		}
+	case '#':
+		switch d {
+		case Right:
+			return Left
+		case Down:
+			return Up
+		case Left:
+			return Right
+		case Up:
+			return Down
+		}
+	case '/':
+		switch d {
+		case Right:
+			return Up
+		case Down:
+			return Left
+		case Left:
+			return Down
+		case Up:
+			return Right
+		}
+	case '\\':
+		switch d {
+		case Right:
+			return Down
+		case Down:
+			return Right
+		case Left:
+			return Up
+		case Up:
+			return Left
+		}
+	}
+	return d
+}
 
 // Stack is a type representing a stack in ><>. It holds the stack values in S, as well as a register. The
 // register may contain data, but will only be considered filled if filledRegister is also true.
@@ -26,6 +94,8 @@ type Stack struct {
 	S              []float64
 	register       float64
 	filledRegister bool
+	taint          []bool // Parallel to S when tracking taint, see WithTaint
+	registerTaint  bool
 }
 
 // NewStack returns a pointer to a Stack populated with s.
@@ -37,55 +107,84 @@ func NewStack(s []float64) *Stack {
 func (s *Stack) Register() {
 	if s.filledRegister {
 		s.Push(s.register)
+		s.pushed(s.registerTaint)
 		s.filledRegister = false
 	} else {
 		s.register = s.Pop()
+		s.registerTaint = s.popped()
 		s.filledRegister = true
 	}
 }
 
+// need panics with ErrStackEmpty unless the stack holds at least n values.
+func (s *Stack) need(n int) {
+	if len(s.S) < n {
+		panic(ErrStackEmpty)
+	}
+}
+
 // Extend implements ":".
 func (s *Stack) Extend() {
+	s.need(1)
 	s.Push(s.S[len(s.S)-1])
+	s.pushed(s.taint != nil && s.taint[len(s.taint)-1])
 }
 
-// Reverse implements "r".
+// Reverse implements "r". It works in place, so it doesn't allocate.
 func (s *Stack) Reverse() {
-	newS := make([]float64, len(s.S))
-	for i, ii := 0, len(s.S)-1; ii >= 0; i, ii = i+1, ii-1 {
-		newS[i] = s.S[ii]
+	for i, ii := 0, len(s.S)-1; i < ii; i, ii = i+1, ii-1 {
+		s.S[i], s.S[ii] = s.S[ii], s.S[i]
+	}
+	for i, ii := 0, len(s.taint)-1; i < ii; i, ii = i+1, ii-1 {
+		s.taint[i], s.taint[ii] = s.taint[ii], s.taint[i]
 	}
-	s.S = newS
 }
 
 // SwapTwo implements "$".
 func (s *Stack) SwapTwo() {
+	s.need(2)
 	x := s.S[len(s.S)-1]
 	s.S[len(s.S)-1] = s.S[len(s.S)-2]
 	s.S[len(s.S)-2] = x
+	s.swapTaint(1, 2)
 }
 
 // SwapThree implements "@": with [1,2,3,4], calling "@" results in [,4,2,3].
 func (s *Stack) SwapThree() {
+	s.need(3)
 	x := s.S[len(s.S)-1]
 	y := s.S[len(s.S)-2]
 	s.S[len(s.S)-1] = y
 	s.S[len(s.S)-2] = s.S[len(s.S)-3]
 	s.S[len(s.S)-3] = x
+	s.swapTaint(1, 2)
+	s.swapTaint(2, 3)
 }
 
-// ShiftRight implements "}".
+// ShiftRight implements "}". It works in place, so it doesn't allocate.
 func (s *Stack) ShiftRight() {
-	newS := make([]float64, 1, len(s.S))
-	newS[0] = s.Pop()
-	s.S = append(newS, s.S...)
+	s.need(1)
+	r := s.S[len(s.S)-1]
+	copy(s.S[1:], s.S)
+	s.S[0] = r
+	if len(s.taint) > 0 {
+		t := s.taint[len(s.taint)-1]
+		copy(s.taint[1:], s.taint)
+		s.taint[0] = t
+	}
 }
 
-// ShiftLeft implements "{".
+// ShiftLeft implements "{". It works in place, so it doesn't allocate.
 func (s *Stack) ShiftLeft() {
+	s.need(1)
 	r := s.S[0]
-	s.S = s.S[1:]
-	s.Push(r)
+	copy(s.S, s.S[1:])
+	s.S[len(s.S)-1] = r
+	if len(s.taint) > 0 {
+		t := s.taint[0]
+		copy(s.taint, s.taint[1:])
+		s.taint[len(s.taint)-1] = t
+	}
 }
 
 // Push appends r to the end of the stack.
@@ -99,11 +198,14 @@ func (s *Stack) Pop() (r float64) {
 		r = s.S[len(s.S)-1]
 		s.S = s.S[:len(s.S)-1]
 	} else {
-		panic("Stack is empty!")
+		panic(ErrStackEmpty)
 	}
 	return
 }
 
+// initialCapacity is the number of values the first stack can grow by before it needs reallocating.
+const initialCapacity = 64
+
 func longestLineLength(lines []string) (l int) {
 	for _, s := range lines {
 		if len(s) > l {
@@ -123,20 +225,54 @@ type CodeBox struct {
 	stacks        []*Stack
 	p             int // Used to keep track of the current stack
 	stringMode    byte
-	compMode      bool
+	mode          Mode
+	ext           map[byte]Instruction
+	input         *bufio.Reader
+	rand          *rand.Rand
+	now           func() time.Time
+	narration     io.Writer
+	sourceMap     *SourceMap
+	regions       []Region
+	round         func(float64) float64
+	division      *Division // Set in integer-only mode
+	negative      Negative
+	events        *EventLog
+	replay        []float64
+	underflow     UnderflowFunc
+	op            byte             // The instruction being executed, if underflow is set
+	ox, oy        int              // Offset of (0,0) as seen by the ><>, after growing the codebox up or left
+	observers     []func(x, y int) // Called with the position of each instruction before it is executed
+	taint         *Taint
+	carry         bool // Whether a tainted value has been popped by the current instruction
+	steps         int
+	outputs       int     // The number of "o" and "n" executed
+	frames        []Frame // The "[" which created each stack after the first
+	calls         []call  // The return stack of CallExtension
+	fastStrings   bool
+	runes         bool
+	wide          map[[2]int]rune // Cells holding code points above 255 in rune mode
+	closed        bool
+	err           error // Why the CodeBox can't run, if its script was empty or an Option was invalid
+	out           io.Writer
+	diagnostics   io.Writer
+	shared        []bool // Rows of the codebox shared with clones, which must be copied before writing
+	deterministic bool
+	ctx           context.Context // The context RunContext is running with, which interrupts Sleep
 }
 
 // NewCodeBox returns a pointer to a new CodeBox. "script" should be a complete ><> script, "stack" should
-// be the initial stack, and compatibilityMode should be set if fishinterpreter.com behaviour is needed.
-func NewCodeBox(script string, stack []float64, compatibilityMode bool) *CodeBox {
+// be the initial stack, and mode chooses which interpreter's behaviour to follow. Any opts are applied to
+// the CodeBox before it is returned. If script is empty or only blank lines, or an option is given an
+// invalid argument, the CodeBox can't run: Err returns why, as do Swim, Step and Run.
+func NewCodeBox(script string, stack []float64, mode Mode, opts ...Option) *CodeBox {
 	cB := new(CodeBox)
 
 	script = strings.Replace(script, "\r", "", -1)
-	if len(script) == 0 || script == "\n" {
-		panic("Cannot accept script of length 0 (No room for the fish to survive).")
-	}
-
 	lines := strings.Split(script, "\n")
+	if longestLineLength(lines) == 0 {
+		// There's no room for the fish to survive, but the CodeBox is given a cell so it can be inspected
+		cB.err, script, lines = ErrEmptyScript, " ", []string{" "}
+	}
 	cB.width = longestLineLength(lines)
 	cB.height = len(lines)
 
@@ -153,17 +289,42 @@ func NewCodeBox(script string, stack []float64, compatibilityMode bool) *CodeBox
 		}
 	}
 
-	cB.stacks = []*Stack{NewStack(stack)}
-	cB.compMode = compatibilityMode
-
+	cB.start(stack, mode, opts)
+	if cB.runes {
+		cB.layoutRunes(script)
+	}
 	return cB
 }
 
+// start sets up the stack and settings of a CodeBox whose codebox has been filled in, then applies opts.
+func (cB *CodeBox) start(stack []float64, mode Mode, opts []Option) {
+	// The stack is copied with room to grow, so typical programs never need to reallocate it.
+	cB.stacks = []*Stack{NewStack(append(make([]float64, 0, len(stack)+initialCapacity), stack...))}
+	cB.mode = mode
+	if mode != Spec {
+		cB.negative = NegativeGrow
+	}
+	for _, opt := range opts {
+		opt(cB)
+	}
+	if cB.input == nil {
+		cB.input = bufio.NewReader(os.Stdin)
+	}
+}
+
 // Exe executes the instruction the ><> is currently on top of. It returns true when it executes ";".
 func (cB *CodeBox) Exe(r byte) bool {
+	if cB.underflow != nil {
+		cB.op = r
+		cB.fill(r)
+	}
 	switch r {
 	default:
-		panic(r)
+		if f, ok := cB.ext[r]; ok {
+			f(cB)
+		} else {
+			panic(&ErrInvalidInstruction{r, cB.fX - cB.ox, cB.fY - cB.oy})
+		}
 	case ' ':
 	case ';':
 		return true
@@ -175,53 +336,14 @@ func (cB *CodeBox) Exe(r byte) bool {
 		cB.fDir = Left
 	case '^':
 		cB.fDir = Up
-	case '|':
-		if cB.fDir == Right {
-			cB.fDir = Left
-		} else if cB.fDir == Left {
-			cB.fDir = Right
-		}
-	case '_':
-		if cB.fDir == Down {
-			cB.fDir = Up
-		} else if cB.fDir == Up {
-			cB.fDir = Down
-		}
-	case '#':
-		switch cB.fDir {
-		case Right:
-			cB.fDir = Left
-		case Down:
-			cB.fDir = Up
-		case Left:
-			cB.fDir = Right
-		case Up:
-			cB.fDir = Down
-		}
-	case '/':
-		switch cB.fDir {
-		case Right:
-			cB.fDir = Up
-		case Down:
-			cB.fDir = Left
-		case Left:
-			cB.fDir = Down
-		case Up:
-			cB.fDir = Right
-		}
-	case '\\':
-		switch cB.fDir {
-		case Right:
-			cB.fDir = Down
-		case Down:
-			cB.fDir = Right
-		case Left:
-			cB.fDir = Up
-		case Up:
-			cB.fDir = Left
-		}
+	case '|', '_', '#', '/', '\\':
+		cB.fDir = cB.fDir.mirror(r)
 	case 'x':
-		cB.fDir = Direction(rand.Int31n(4))
+		if cB.rand != nil {
+			cB.fDir = Direction(cB.rand.Int31n(4))
+		} else {
+			cB.fDir = Direction(rand.Int31n(4))
+		}
 	case '"', '\'':
 		if cB.stringMode == 0 {
 			cB.stringMode = r
@@ -235,9 +357,19 @@ func (cB *CodeBox) Exe(r byte) bool {
 	case '&':
 		cB.Register()
 	case 'o':
-		fmt.Print(string(byte(cB.Pop())))
+		v := cB.Pop()
+		cB.record(OutputChar, v)
+		cB.output()
+		if cB.runes {
+			fmt.Fprint(cB.stdout(), string(rune(v)))
+		} else {
+			fmt.Fprint(cB.stdout(), string(byte(v)))
+		}
 	case 'n':
-		fmt.Printf("%v", cB.Pop())
+		v := cB.Pop()
+		cB.record(OutputNum, v)
+		cB.output()
+		fmt.Fprintf(cB.stdout(), "%v", v)
 	case 'r':
 		cB.ReverseStack()
 	case '+':
@@ -251,11 +383,11 @@ func (cB *CodeBox) Exe(r byte) bool {
 	case ',':
 		x := cB.Pop()
 		y := cB.Pop()
-		cB.Push(y / x)
+		cB.Push(cB.divide(y, x))
 	case '%':
 		x := cB.Pop()
 		y := cB.Pop()
-		cB.Push(float64(int64(y) % int64(x)))
+		cB.Push(cB.modulo(y, x))
 	case '=':
 		if cB.Pop() == cB.Pop() {
 			cB.Push(1)
@@ -285,8 +417,8 @@ func (cB *CodeBox) Exe(r byte) bool {
 			cB.Move()
 		}
 	case '.':
-		cB.fY = int(cB.Pop())
-		cB.fX = int(cB.Pop())
+		cB.fY = toInt(cB.Pop(), "Coordinate") + cB.oy
+		cB.fX = toInt(cB.Pop(), "Coordinate") + cB.ox
 	case ':':
 		cB.ExtendStack()
 	case '~':
@@ -306,22 +438,43 @@ func (cB *CodeBox) Exe(r byte) bool {
 	case 'l':
 		cB.Push(cB.StackLength())
 	case 'g':
-		cB.Push(float64(cB.box[int(cB.Pop())][int(cB.Pop())]))
+		y := toInt(cB.Pop(), "Coordinate")
+		x := toInt(cB.Pop(), "Coordinate")
+		cB.carry = cB.taint != nil && cB.taint.cells[[2]int{x, y}] || cB.carry
+		r := cB.get(x, y)
+		if r == ' ' && cB.mode != Spec {
+			r = 0 // Empty cells are 0 on the websites
+		}
+		cB.Push(float64(r))
 	case 'p':
-		cB.box[int(cB.Pop())][int(cB.Pop())] = byte(cB.Pop())
-	case 'i':
-		r := float64(-1)
-		b := byte(0)
-		select {
-		case b = <-reader:
-			r = float64(b)
-		default:
+		y := toInt(cB.Pop(), "Coordinate")
+		x := toInt(cB.Pop(), "Coordinate")
+		cB.put(x, y, cB.toCell(cB.Pop()))
+		if cB.taint != nil {
+			cB.taint.cells[[2]int{x, y}] = cB.carry
 		}
+	case 'i':
+		r := cB.readByte()
+		cB.carry = cB.taint != nil
 		cB.Push(r)
 	}
 	return false
 }
 
+// readByte reads a byte of input for the ><>, returning -1 if no input was available.
+func (cB *CodeBox) readByte() float64 {
+	r := float64(-1)
+	if cB.replay != nil {
+		if len(cB.replay) > 0 {
+			r, cB.replay = cB.replay[0], cB.replay[1:]
+		}
+	} else if b, err := cB.input.ReadByte(); err == nil {
+		r = float64(b)
+	}
+	cB.record(InputByte, r)
+	return r
+}
+
 // Move changes the fish's x/y coordinates based on CodeBox.fDir.
 func (cB *CodeBox) Move() {
 	switch cB.fDir {
@@ -346,41 +499,139 @@ func (cB *CodeBox) Move() {
 			cB.fY = cB.height - 1
 		}
 	}
+	if cB.fX < 0 || cB.fX >= cB.width || cB.fY < 0 || cB.fY >= cB.height {
+		panic(ErrOutOfBounds) // After "." jumped beyond the codebox
+	}
 }
 
-// Swim causes the ><> to execute an instruction, then move. It returns true when it encounters ";".
-func (cB *CodeBox) Swim() bool {
+// Swim causes the ><> to execute an instruction, then move. It returns true when it encounters ";". If the
+// ><> can't execute its instruction, it returns a *Failure instead, which PrintFailure can report; the
+// CodeBox shouldn't be used after a failure. Swim never panics, even if an extension or observer does, so
+// neither do Step and Run.
+func (cB *CodeBox) Swim() (done bool, err error) {
+	if cB.closed {
+		return false, ErrClosed
+	} else if cB.err != nil {
+		return false, cB.err
+	}
+	x, y := cB.fX, cB.fY
 	defer func() {
 		if r := recover(); r != nil {
-			cB.PrintBox()
-			fmt.Println("Stack:", cB.Stack())
-			fmt.Println("something smells fishy...")
-			os.Exit(1)
+			err = cB.fail(x, y, r)
 		}
 	}()
+	return cB.swim(), nil
+}
 
-	if r := cB.box[cB.fY][cB.fX]; cB.stringMode != 0 && r != cB.stringMode {
-		cB.Push(float64(r))
+// swim is Swim without recovering: it panics if the ><> can't execute its instruction.
+func (cB *CodeBox) swim() bool {
+	var before []float64
+	x, y, d := cB.fX, cB.fY, cB.fDir
+	for _, f := range cB.observers {
+		f(x, y)
+	}
+	cB.carry = false
+	cB.steps++
+	r := cB.box[y][x]
+	if cB.narration != nil {
+		if cB.underflow != nil && cB.stringMode == 0 {
+			// Fill the stack before Exe does, so before holds the values r pops
+			cB.op = r
+			cB.fill(r)
+		}
+		before = append(before, cB.Stack()...)
+	}
+	if cB.stringMode != 0 && r != cB.stringMode {
+		if cB.fastStrings && cB.narration == nil {
+			cB.pushString()
+		} else {
+			cB.Push(float64(cB.cell(x, y)))
+			cB.narrate(x, y, d, r, true, before)
+		}
 	} else if cB.Exe(r) {
+		cB.narrate(x, y, d, r, false, before)
 		return true
+	} else {
+		cB.narrate(x, y, d, r, false, before)
 	}
 	cB.Move()
 	return false
 }
 
+// pushString pushes the characters from the ><>'s position up to the closing quote, wrapping around the
+// codebox, and leaves the ><> on the last one. At most one lap of the codebox is pushed, so a string which
+// is never closed doesn't hang.
+func (cB *CodeBox) pushString() {
+	n, lap := 1, cB.width
+	if cB.fDir == Up || cB.fDir == Down {
+		lap = cB.height
+	}
+	for x, y := cB.next(cB.fX, cB.fY, cB.fDir, 1); n < lap && cB.box[y][x] != cB.stringMode; n++ {
+		x, y = cB.next(x, y, cB.fDir, 1)
+	}
+	if s := cB.stacks[cB.p]; cap(s.S)-len(s.S) < n {
+		s.S = append(make([]float64, 0, len(s.S)+n), s.S...)
+	}
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			cB.Move()
+		}
+		cB.Push(float64(cB.cell(cB.fX, cB.fY)))
+	}
+}
+
+// Now returns the current time, as seen by the ><>. Extensions should use it rather than time.Now.
+func (cB *CodeBox) Now() time.Time {
+	if cB.now != nil {
+		return cB.now()
+	}
+	return time.Now()
+}
+
+// Sleep pauses the ><> for d. Extensions should use it rather than time.Sleep, so that deterministic runs,
+// whose clock never advances, don't wait, and so RunContext can interrupt the pause. If it does, the
+// instruction fails with the context's error.
+func (cB *CodeBox) Sleep(d time.Duration) {
+	if cB.deterministic {
+		return
+	}
+	if cB.ctx == nil {
+		time.Sleep(d)
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-cB.ctx.Done():
+		panic(cB.ctx.Err())
+	}
+}
+
 // Stack returns the underlying Stack slice.
 func (cB *CodeBox) Stack() []float64 {
 	return cB.stacks[cB.p].S
 }
 
-// Push appends r to the end of the current stack.
+// Push appends r to the end of the current stack, rounded to the CodeBox's precision.
 func (cB *CodeBox) Push(r float64) {
+	if cB.round != nil {
+		r = cB.round(r)
+	}
 	cB.stacks[cB.p].Push(r)
+	cB.stacks[cB.p].pushed(cB.carry)
 }
 
 // Pop removes the value on the end of the current stack and returns it.
 func (cB *CodeBox) Pop() float64 {
-	return cB.stacks[cB.p].Pop()
+	if len(cB.stacks[cB.p].S) == 0 && cB.underflow != nil {
+		if v, ok := cB.underflow(cB.op); ok {
+			return v
+		}
+	}
+	v := cB.stacks[cB.p].Pop()
+	cB.carry = cB.stacks[cB.p].popped() || cB.carry
+	return v
 }
 
 // StackLength implements "l" on the current stack.
@@ -425,56 +676,63 @@ func (cB *CodeBox) StackShiftLeft() {
 
 // CloseStack implements "]".
 func (cB *CodeBox) CloseStack() {
+	if cB.p == 0 {
+		panic(ErrOnlyStack)
+	}
 	cB.p--
-	if cB.compMode {
+	cB.frames = cB.frames[:cB.p]
+	if cB.mode == FishLanguageCom {
 		cB.stacks[cB.p+1].Reverse() // This is done to match the fishlanguage.com interpreter...
 	}
 	cB.stacks[cB.p].S = append(cB.stacks[cB.p].S, cB.stacks[cB.p+1].S...)
+	if cB.taint != nil {
+		cB.stacks[cB.p].taint = append(cB.stacks[cB.p].taint, cB.stacks[cB.p+1].taint...)
+	}
 }
 
 // NewStack implements "[".
 func (cB *CodeBox) NewStack(n int) {
+	if n < 0 || n > len(cB.stacks[cB.p].S) {
+		panic(ErrStackEmpty) // Checked first, so the stacks are left as they were
+	}
 	cB.p++
+	cB.frames = append(cB.frames, Frame{cB.fX, cB.fY, cB.steps})
+	// The new stack shares the end of the old one's array, so "]" can merge them without allocating.
+	below := cB.stacks[cB.p-1]
+	s := below.S[len(below.S)-n:]
+	below.S = below.S[:len(below.S)-n]
 	if cB.p == len(cB.stacks) {
-		cB.stacks = append(cB.stacks, NewStack(cB.stacks[cB.p-1].S[len(cB.stacks[cB.p-1].S)-n:]))
-		cB.stacks[cB.p-1].S = cB.stacks[cB.p-1].S[:len(cB.stacks[cB.p-1].S)-n]
+		cB.stacks = append(cB.stacks, NewStack(s))
 	} else {
-		cB.stacks[cB.p].S = cB.stacks[cB.p-1].S[len(cB.stacks[cB.p-1].S)-n:]
+		cB.stacks[cB.p].S = s
 		cB.stacks[cB.p].filledRegister = false
 	}
-	if cB.compMode {
+	if cB.taint != nil {
+		t := cB.stacks[cB.p-1].taint
+		cB.stacks[cB.p].taint = t[len(t)-n:]
+		cB.stacks[cB.p-1].taint = t[:len(t)-n]
+	}
+	if cB.mode == FishLanguageCom {
 		cB.stacks[cB.p].Reverse() // This is done to match the fishlanguage.com interpreter...
 	}
 }
 
-// PrintBox outputs the codebox to stdout.
+// PrintBox outputs the codebox to the diagnostics writer.
 func (cB *CodeBox) PrintBox() {
-	fmt.Println()
+	fmt.Fprintln(cB.diag())
 	for y, line := range cB.box {
-		for x, r := range line {
+		for x := range line {
 			if x != cB.fX || y != cB.fY {
-				fmt.Print(" " + string(rune(r)) + " ")
+				fmt.Fprint(cB.diag(), cB.highlight(x, y, " "+string(cB.cell(x, y))+" "))
 			} else {
-				fmt.Print("*" + string(rune(r)) + "*")
+				fmt.Fprint(cB.diag(), cB.highlight(x, y, "*"+string(cB.cell(x, y))+"*"))
 			}
 		}
-		fmt.Println()
+		fmt.Fprintln(cB.diag())
 	}
+	cB.printLegend()
 }
 
 func init() {
 	rand.Seed(int64(time.Now().Nanosecond()))
-	reader = make(chan byte, 1024)
-	go func() {
-		var err error
-		b := make([]byte, 1024)
-		for err == nil {
-			n, err := os.Stdin.Read(b)
-			if err == nil {
-				for i := 0;i < n;i++ {
-					reader <- b[i]
-				}
-			}
-		}
-	}()
 }
@@ -13,19 +13,24 @@ const (
 	TESTVALUE4 = 4
 )
 
-func runscript(script string, initialstack []float64, compMode bool) *CodeBox {
-	cB := NewCodeBox(script, initialstack, compMode)
+func runscript(script string, initialstack []float64, mode Mode) *CodeBox {
+	cB := NewCodeBox(script, initialstack, mode)
 	now := time.Now()
-	for !cB.Swim() {
+	for {
+		done, err := cB.Swim()
+		if err != nil {
+			log.Fatalln(err)
+		} else if done {
+			return cB
+		}
 		if time.Since(now) >= time.Second {
 			log.Fatalln("script taking too long...")
 		}
 	}
-	return cB
 }
 
 func TestStackRegister(t *testing.T) {
-	cB := runscript("&;", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3}, false)
+	cB := runscript("&;", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3}, Spec)
 	s := cB.stacks[0]
 	if len(s.S) != 2 || s.register != TESTVALUE3 || s.S[0] != TESTVALUE1 || !s.filledRegister {
 		t.FailNow()
@@ -37,7 +42,7 @@ func TestStackRegister(t *testing.T) {
 }
 
 func TestStackExtend(t *testing.T) {
-	cB := runscript(":;", []float64{TESTVALUE1, TESTVALUE2}, false)
+	cB := runscript(":;", []float64{TESTVALUE1, TESTVALUE2}, Spec)
 	s := cB.stacks[0]
 	if len(s.S) != 3 || s.S[2] != TESTVALUE2 {
 		t.FailNow()
@@ -45,7 +50,7 @@ func TestStackExtend(t *testing.T) {
 }
 
 func TestStackReverse(t *testing.T) {
-	cB := runscript("r;", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3}, false)
+	cB := runscript("r;", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3}, Spec)
 	s := cB.stacks[0]
 	if s.S[0] != TESTVALUE3 || s.S[1] != TESTVALUE2 || s.S[2] != TESTVALUE1 {
 		t.FailNow()
@@ -53,7 +58,7 @@ func TestStackReverse(t *testing.T) {
 }
 
 func TestStackSwapTwo(t *testing.T) {
-	cB := runscript("$;", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3}, false)
+	cB := runscript("$;", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3}, Spec)
 	s := cB.stacks[0]
 	if s.S[0] != TESTVALUE1 || s.S[1] != TESTVALUE3 || s.S[2] != TESTVALUE2 {
 		t.FailNow()
@@ -61,7 +66,7 @@ func TestStackSwapTwo(t *testing.T) {
 }
 
 func TestStackSwapThree(t *testing.T) {
-	cB := runscript("@;", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3, TESTVALUE4}, false)
+	cB := runscript("@;", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3, TESTVALUE4}, Spec)
 	s := cB.stacks[0]
 	if s.S[0] != TESTVALUE1 || s.S[1] != TESTVALUE4 || s.S[2] != TESTVALUE2 || s.S[3] != TESTVALUE3 {
 		t.FailNow()
@@ -69,7 +74,7 @@ func TestStackSwapThree(t *testing.T) {
 }
 
 func TestStackShiftLeft(t *testing.T) {
-	cB := runscript("{;", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3, TESTVALUE4}, false)
+	cB := runscript("{;", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3, TESTVALUE4}, Spec)
 	s := cB.stacks[0]
 	if s.S[0] != TESTVALUE2 || s.S[1] != TESTVALUE3 || s.S[2] != TESTVALUE4 || s.S[3] != TESTVALUE1 {
 		t.FailNow()
@@ -77,7 +82,7 @@ func TestStackShiftLeft(t *testing.T) {
 }
 
 func TestStackShiftRight(t *testing.T) {
-	cB := runscript("};", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3, TESTVALUE4}, false)
+	cB := runscript("};", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3, TESTVALUE4}, Spec)
 	s := cB.stacks[0]
 	if s.S[0] != TESTVALUE4 || s.S[1] != TESTVALUE1 || s.S[2] != TESTVALUE2 || s.S[3] != TESTVALUE3 {
 		t.FailNow()
@@ -85,7 +90,7 @@ func TestStackShiftRight(t *testing.T) {
 }
 
 func TestNewStackCloseStack(t *testing.T) {
-	cB := NewCodeBox("[]", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3, TESTVALUE4, 2}, false)
+	cB := NewCodeBox("[]", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3, TESTVALUE4, 2}, Spec)
 	cB.Swim()
 	s := cB.stacks[0]
 	s2 := cB.stacks[1]
@@ -101,7 +106,7 @@ func TestNewStackCloseStack(t *testing.T) {
 }
 
 func TestNewStackCloseStackCompatibility(t *testing.T) {
-	cB := NewCodeBox("[]", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3, TESTVALUE4, 2}, true)
+	cB := NewCodeBox("[]", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3, TESTVALUE4, 2}, FishLanguageCom)
 	cB.Swim()
 	s := cB.stacks[0]
 	s2 := cB.stacks[1]
@@ -116,20 +121,34 @@ func TestNewStackCloseStackCompatibility(t *testing.T) {
 	}
 }
 
+func TestCloseOnlyStack(t *testing.T) {
+	if fail := swimAll(NewCodeBox("];", []float64{1}, Spec)); fail != "Cannot close the only stack!" {
+		t.Error(fail)
+	}
+}
+
 func TestPrintBox(t *testing.T) {
-	cB := NewCodeBox(`"Hello test!";`, []float64{}, false)
+	cB := NewCodeBox(`"Hello test!";`, []float64{}, Spec)
 	cB.PrintBox()
 }
 
+func TestNewStackReused(t *testing.T) {
+	cB := runscript("1[]1[];", []float64{TESTVALUE1}, Spec)
+	s := cB.stacks[0]
+	if len(s.S) != 1 || s.S[0] != TESTVALUE1 {
+		t.FailNow()
+	}
+}
+
 func TestStackLength(t *testing.T) {
-	cB := NewCodeBox(";", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3}, false)
+	cB := NewCodeBox(";", []float64{TESTVALUE1, TESTVALUE2, TESTVALUE3}, Spec)
 	if cB.StackLength() != 3 {
 		t.Fail()
 	}
 }
 
 func TestStackReturn(t *testing.T) {
-	cB := NewCodeBox(";", []float64{TESTVALUE1, TESTVALUE3}, false)
+	cB := NewCodeBox(";", []float64{TESTVALUE1, TESTVALUE3}, Spec)
 	s := cB.Stack()
 	if s[0] != TESTVALUE1 || s[1] != TESTVALUE3 {
 		t.Fail()
@@ -137,27 +156,27 @@ func TestStackReturn(t *testing.T) {
 }
 
 func TestMovement(t *testing.T) {
-	cB := NewCodeBox(">;", []float64{}, false)
+	cB := NewCodeBox(">;", []float64{}, Spec)
 	cB.Swim()
-	if !cB.Swim() {
+	if done, _ := cB.Swim(); !done {
 		t.Fail()
 	}
 	
-	cB = NewCodeBox("<;", []float64{}, false)
+	cB = NewCodeBox("<;", []float64{}, Spec)
 	cB.Swim()
-	if !cB.Swim() {
+	if done, _ := cB.Swim(); !done {
 		t.Fail()
 	}
 	
-	cB = NewCodeBox("^\n;", []float64{}, false)
+	cB = NewCodeBox("^\n;", []float64{}, Spec)
 	cB.Swim()
-	if !cB.Swim() {
+	if done, _ := cB.Swim(); !done {
 		t.Fail()
 	}
 	
-	cB = NewCodeBox("v\n;", []float64{}, false)
+	cB = NewCodeBox("v\n;", []float64{}, Spec)
 	cB.Swim()
-	if !cB.Swim() {
+	if done, _ := cB.Swim(); !done {
 		t.Fail()
 	}
 }
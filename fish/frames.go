@@ -0,0 +1,44 @@
+package fish
+
+import (
+	"fmt"
+	"io"
+)
+
+// Frame records the "[" which created one of the ><>'s stacks.
+type Frame struct {
+	X, Y int
+	Step int // The step on which "[" was executed, counting from 1
+}
+
+func (f Frame) String() string {
+	return fmt.Sprintf("[ at (%d,%d), step %d", f.X, f.Y, f.Step)
+}
+
+// StackTrace returns the "[" which created each stack enclosing the current one, innermost first, much like
+// a call stack.
+func (cB *CodeBox) StackTrace() []Frame {
+	trace := make([]Frame, len(cB.frames))
+	for i, f := range cB.frames {
+		trace[len(trace)-1-i] = f
+	}
+	return trace
+}
+
+// printStackTrace writes trace to w, if the ><> was in a nested stack, using m to show where each "[" came
+// from if it's set.
+func printStackTrace(w io.Writer, trace []Frame, m *SourceMap) {
+	if len(trace) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Stack trace:")
+	for _, f := range trace {
+		if m != nil {
+			if o, ok := m.Lookup(f.X, f.Y); ok {
+				fmt.Fprintf(w, "\t%v (%v)\n", f, o)
+				continue
+			}
+		}
+		fmt.Fprintf(w, "\t%v\n", f)
+	}
+}
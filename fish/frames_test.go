@@ -0,0 +1,22 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestStackTrace(t *testing.T) {
+	cB := NewCodeBox("11[01[]v\n    ;0[<", nil, Spec)
+	for i := 0; i < 10; i++ {
+		cB.swim()
+	}
+	trace := cB.StackTrace()
+	if len(trace) != 2 {
+		t.Fatal(trace)
+	}
+	if trace[0] != (Frame{6, 1, 10}) || trace[1] != (Frame{2, 0, 3}) {
+		t.Error(trace)
+	}
+	if got := trace[1].String(); got != "[ at (2,0), step 3" {
+		t.Error(got)
+	}
+}
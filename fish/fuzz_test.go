@@ -0,0 +1,86 @@
+package fish
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+// fuzzAlphabet is the instructions generated programs are made of. "[", "]" and "%" are left out, as the
+// Modes are meant to differ there, and so are "o" and "n" to keep the test output clean. "g" is meant to
+// differ too, on empty cells, so FuzzModes only compares it between the websites.
+const fuzzAlphabet = "0123456789abcdef+-*,=)(!?:~$@}{lgpi&r;><^v/\\|_#x .\"'"
+
+// fuzzScript turns arbitrary bytes into a script made of fuzzAlphabet, 8 cells wide.
+func fuzzScript(data []byte) string {
+	script := make([]byte, 0, len(data)+len(data)/8)
+	for i, b := range data {
+		if i > 0 && i%8 == 0 {
+			script = append(script, '\n')
+		}
+		script = append(script, fuzzAlphabet[int(b)%len(fuzzAlphabet)])
+	}
+	return string(script)
+}
+
+type outcome struct {
+	steps int
+	fail  string
+	stack []float64
+}
+
+// runMode runs script in the given mode for at most maxSteps steps. Negative coordinates are errors in
+// both modes, as they are meant to differ there.
+func runMode(script string, input []byte, mode Mode, maxSteps int) (o outcome) {
+	cB := NewCodeBox(script, nil, mode, WithInput(bytes.NewReader(input)), WithDeterministic(),
+		WithNegativeCoordinates(NegativeError))
+	defer func() {
+		if r := recover(); r != nil {
+			o.fail = failure(r)
+		}
+		o.stack = cB.Stack()
+	}()
+	for o.steps = 0; o.steps < maxSteps; o.steps++ {
+		if cB.swim() {
+			break
+		}
+	}
+	return
+}
+
+func (o outcome) equal(o2 outcome) bool {
+	if o.steps != o2.steps || o.fail != o2.fail || len(o.stack) != len(o2.stack) {
+		return false
+	}
+	for i := range o.stack {
+		if math.Float64bits(o.stack[i]) != math.Float64bits(o2.stack[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// FuzzModes runs generated programs in each Mode, and fails if they behave
+// differently. Any new execution backend should be added to the comparison.
+func FuzzModes(f *testing.F) {
+	f.Add([]byte("seed"), []byte("input"))
+	f.Add([]byte{0, 1, 30, 40, 50, 60, 70, 80, 90, 100, 110, 120}, []byte{})
+	f.Add([]byte("\x10\x11\x12\x13\x14\x15\x16\x17\x18\x19"), []byte("ab"))
+	f.Fuzz(func(t *testing.T, data, input []byte) {
+		if len(data) == 0 {
+			return
+		}
+		script := fuzzScript(data)
+		base, modes := Spec, []Mode{FishLanguageCom, FishInterpreterCom}
+		if strings.IndexByte(script, 'g') >= 0 {
+			base, modes = FishLanguageCom, []Mode{FishInterpreterCom}
+		}
+		want := runMode(script, input, base, 1000)
+		for _, mode := range modes {
+			if o := runMode(script, input, mode, 1000); !want.equal(o) {
+				t.Errorf("%q diverged: %v %+v, %v %+v", script, base, want, mode, o)
+			}
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package fish
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+)
+
+// The headless protocol lets editors and other programs drive a Debugger. Messages are JSON objects, one
+// per line. Each headlessRequest holds a command, as typed at Serve's prompt, and is answered by a
+// headlessReply with the command's results and the ><>'s state afterwards.
+
+// headlessRequest asks the Debugger to execute a command.
+type headlessRequest struct {
+	ID      json.RawMessage `json:"id,omitempty"` // Returned in the reply, so requests can be matched with replies
+	Command string          `json:"command"`
+}
+
+// headlessReply is the Debugger's reply to a headlessRequest.
+type headlessReply struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Result string          `json:"result"`          // What Serve would have shown for the command
+	Output string          `json:"output"`          // What the ><> output while executing the command
+	State  *headlessState  `json:"state,omitempty"` // Nil if the request was invalid
+	Quit   bool            `json:"quit,omitempty"`  // Set in the reply to "quit", after which no more are read
+	Error  string          `json:"error,omitempty"` // Why the request was invalid, if it was
+}
+
+// headlessState is the state of the ><>, as shown by the "print" command.
+type headlessState struct {
+	Step     int               `json:"step"`
+	X        int               `json:"x"`
+	Y        int               `json:"y"`
+	Dir      string            `json:"dir"`
+	Stacks   [][]headlessValue `json:"stacks"` // Oldest first, so the current stack is last
+	Register *headlessValue    `json:"register"`
+	Done     bool              `json:"done"`              // Whether the ><> has halted
+	Failure  string            `json:"failure,omitempty"` // Why the ><> failed, if it did
+}
+
+// headlessValue is a value on a stack, which is encoded as a JSON number if it's finite, and otherwise as
+// the string "NaN", "+Inf" or "-Inf".
+type headlessValue float64
+
+func (v headlessValue) MarshalJSON() ([]byte, error) {
+	f := float64(v)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return []byte(strconv.Quote(strconv.FormatFloat(f, 'g', -1, 64))), nil
+	}
+	return []byte(strconv.FormatFloat(f, 'g', -1, 64)), nil
+}
+
+// ServeJSON reads commands from r and writes replies to w, usually os.Stdin and os.Stdout, until "quit" or
+// the end of r. Each line of r is a JSON object such as {"id":1,"command":"step 2"}, taking the commands
+// listed by "help", and is answered by a line such as
+//
+//	{"id":1,"result":"...","output":"","state":{"step":2,"x":2,"y":0,"dir":"Right","stacks":[[1,2]],"register":null,"done":false}}
+//
+// where result is what Serve would have shown, including the codebox, output is what the ><> output, and
+// state describes the ><> afterwards, with its "failure" if it failed. The ><>'s output and diagnostics are
+// captured while ServeJSON runs, so they can't interfere with the replies. It only returns an error if r
+// can't be read or w can't be written.
+func (d *Debugger) ServeJSON(r io.Reader, w io.Writer) error {
+	result, output := new(bytes.Buffer), new(bytes.Buffer)
+	diag, out := d.cB.diagnostics, d.cB.out
+	d.cB.diagnostics, d.cB.out = result, output
+	defer func() {
+		d.cB.diagnostics, d.cB.out = diag, out
+	}()
+	enc := json.NewEncoder(w)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		var req headlessRequest
+		var reply headlessReply
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			reply.Error = err.Error()
+		} else {
+			reply.ID = req.ID
+			reply.Quit = d.command(result, req.Command)
+			reply.Result, reply.Output, reply.State = result.String(), output.String(), d.state()
+		}
+		result.Reset()
+		output.Reset()
+		if err := enc.Encode(reply); err != nil {
+			return err
+		}
+		if reply.Quit {
+			break
+		}
+	}
+	return sc.Err()
+}
+
+// state returns the state of the ><> for a headlessReply.
+func (d *Debugger) state() *headlessState {
+	s := d.cB.Snapshot()
+	st := &headlessState{Step: s.Step, X: s.X, Y: s.Y, Dir: s.Dir.String(), Done: d.done,
+		Stacks: make([][]headlessValue, len(s.Stacks))}
+	for i, stack := range s.Stacks {
+		st.Stacks[i] = make([]headlessValue, len(stack))
+		for ii, v := range stack {
+			st.Stacks[i][ii] = headlessValue(v)
+		}
+	}
+	if s.Register != nil {
+		v := headlessValue(*s.Register)
+		st.Register = &v
+	}
+	if d.err != nil {
+		st.Failure = d.err.Error()
+	}
+	return st
+}
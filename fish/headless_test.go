@@ -0,0 +1,72 @@
+package fish
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestServeJSON(t *testing.T) {
+	diag := new(bytes.Buffer)
+	cB := NewCodeBox("1&2n~~~", []float64{math.Inf(1), math.NaN()}, Spec, WithDiagnostics(diag))
+	requests := `{"id":1,"command":"b 4,0"}
+not json
+{"id":"two","command":"c"}
+{"command":"s 3"}
+{"id":4,"command":"q"}
+{"id":5,"command":"c"}
+`
+	out := new(bytes.Buffer)
+	if err := NewDebugger(cB).ServeJSON(strings.NewReader(requests), out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d replies, want 5: %q", len(lines), out)
+	}
+	var replies [5]struct {
+		ID     interface{}
+		Result string
+		Output string
+		State  *struct {
+			Step     int
+			X        int
+			Dir      string
+			Stacks   [][]interface{}
+			Register interface{}
+			Done     bool
+			Failure  string
+		}
+		Quit  bool
+		Error string
+	}
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &replies[i]); err != nil {
+			t.Fatalf("reply %d: %v: %s", i, err, line)
+		}
+	}
+
+	if r := replies[0]; r.ID != 1.0 || r.Result != "Breakpoint at (4,0)\n" || r.State.Step != 0 {
+		t.Errorf("got %+v", r)
+	}
+	if r := replies[1]; r.Error == "" || r.State != nil {
+		t.Errorf("got %+v for invalid JSON", r)
+	}
+	if r := replies[2]; r.ID != "two" || r.Output != "2" || r.State.X != 4 || r.State.Register != 1.0 ||
+		r.State.Stacks[0][0] != "+Inf" || r.State.Stacks[0][1] != "NaN" ||
+		!strings.Contains(r.Result, "Breakpoint at (4,0)") {
+		t.Errorf("got %+v", r)
+	}
+	if r := replies[3]; r.ID != nil || r.State.Failure == "" || len(r.State.Stacks[0]) != 0 ||
+		!strings.Contains(r.Result, "Stack is empty!") {
+		t.Errorf("got %+v", r)
+	}
+	if r := replies[4]; !r.Quit {
+		t.Errorf("got %+v", r)
+	}
+	if diag.Len() != 0 {
+		t.Errorf("wrote %q to the diagnostics", diag)
+	}
+}
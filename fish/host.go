@@ -0,0 +1,131 @@
+package fish
+
+import (
+	"context"
+	"errors"
+)
+
+// Runner is implemented by interpreters which can run a ><> until it halts. Hosts which only need to run
+// scripts should depend on Runner rather than *CodeBox, so they can substitute a fake in their own tests.
+type Runner interface {
+	// Run runs the ><> until it halts, for at most maxSteps steps if maxSteps is positive. It returns the
+	// number of steps taken, including one which failed, and the first error encountered, or ErrMaxSteps if
+	// the ><> didn't halt.
+	Run(maxSteps int) (steps int, err error)
+}
+
+// Stepper is implemented by interpreters which can run a ><> one tick at a time.
+type Stepper interface {
+	// Step executes a single tick, returning true once the ><> has halted.
+	Step() (done bool, err error)
+}
+
+// Inspector is implemented by interpreters whose state can be examined between ticks.
+type Inspector interface {
+	// Stack returns the current stack. It shouldn't be modified.
+	Stack() []float64
+	// Position returns the position of the ><>, as seen by the ><>.
+	Position() (x, y int)
+	// Direction returns the direction the ><> is swimming in.
+	Direction() Direction
+	// Steps returns the number of ticks executed so far.
+	Steps() int
+}
+
+var (
+	_ Runner    = (*CodeBox)(nil)
+	_ Stepper   = (*CodeBox)(nil)
+	_ Inspector = (*CodeBox)(nil)
+)
+
+// Run calls Swim until the ><> halts or fails, or until it has taken maxSteps steps if maxSteps is
+// positive. It returns the number of steps taken, and the *Failure if the ><> failed, or ErrMaxSteps if it
+// didn't halt in time.
+func (cB *CodeBox) Run(maxSteps int) (steps int, err error) {
+	return cB.RunContext(context.Background(), maxSteps)
+}
+
+// ctxCheck is how many steps RunContext takes between checking whether its context is done.
+const ctxCheck = 1024
+
+// RunContext is Run, but it also stops when ctx is done, returning ctx.Err(), so a ><> can be cancelled or
+// given a deadline from another goroutine. The context is checked every few steps, and interrupts
+// CodeBox.Sleep, but an "i" waiting for input can't be interrupted.
+func (cB *CodeBox) RunContext(ctx context.Context, maxSteps int) (steps int, err error) {
+	if cB.closed {
+		return 0, ErrClosed
+	} else if cB.err != nil {
+		return 0, cB.err
+	}
+	cB.ctx = ctx
+	defer func() {
+		cB.ctx = nil
+	}()
+	done := false
+	for ; !done; steps++ {
+		if maxSteps > 0 && steps == maxSteps {
+			return steps, ErrMaxSteps
+		}
+		if steps%ctxCheck == 0 {
+			if err = ctx.Err(); err != nil {
+				return steps, err
+			}
+		}
+		if done, err = cB.Swim(); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+				return steps + 1, ctxErr
+			}
+			return steps + 1, err
+		}
+	}
+	return steps, nil
+}
+
+// Err returns why the CodeBox can't run, such as ErrEmptyScript, or an error wrapping ErrOutOfRange if an
+// Option was given an invalid argument. It returns nil if the CodeBox can run.
+func (cB *CodeBox) Err() error {
+	return cB.err
+}
+
+// invalid records err as the reason the CodeBox can't run, unless it already has one.
+func (cB *CodeBox) invalid(err error) {
+	if cB.err == nil {
+		cB.err = err
+	}
+}
+
+// Position returns the position of the ><>, as seen by the ><>.
+func (cB *CodeBox) Position() (x, y int) {
+	return cB.fX - cB.ox, cB.fY - cB.oy
+}
+
+// Direction returns the direction the ><> is swimming in.
+func (cB *CodeBox) Direction() Direction {
+	return cB.fDir
+}
+
+// Steps returns the number of ticks executed so far.
+func (cB *CodeBox) Steps() int {
+	return cB.steps
+}
+
+// Close releases the CodeBox's resources, so a host can end its life deterministically. It flushes the
+// writer given to WithOutput if that has a Flush method, as a *bufio.Writer does, and drops the codebox,
+// stacks and input so they can be collected even while the CodeBox is still referenced. Readers and writers
+// given to options belong to the host, and aren't closed. Afterwards Swim, Step, Run and Close return
+// ErrClosed, and the stack is empty.
+func (cB *CodeBox) Close() error {
+	if cB.closed {
+		return ErrClosed
+	}
+	cB.closed = true
+	var err error
+	if f, ok := cB.out.(interface{ Flush() error }); ok {
+		err = f.Flush()
+	}
+	cB.box, cB.wide, cB.shared = nil, nil, nil
+	cB.stacks, cB.p, cB.frames, cB.calls = []*Stack{NewStack(nil)}, 0, nil, nil
+	cB.input, cB.replay = nil, nil
+	cB.observers, cB.narration, cB.events, cB.taint = nil, nil, nil, nil
+	return err
+}
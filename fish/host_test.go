@@ -0,0 +1,97 @@
+package fish
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	cB := NewCodeBox("12+;", nil, Spec)
+	if n, err := cB.Run(0); err != nil || n != 4 {
+		t.Fatalf("got %d, %v, want 4, nil", n, err)
+	}
+	if s := cB.Stack(); len(s) != 1 || s[0] != 3 {
+		t.Errorf("got stack %v, want [3]", s)
+	}
+
+	n, err := NewCodeBox("1+;", nil, Spec).Run(0)
+	if f, ok := err.(*Failure); !ok || f.X != 1 || f.Op != '+' || n != 2 {
+		t.Errorf("got %d, %v, want a failure at '+' on step 2", n, err)
+	}
+
+	cB = NewCodeBox("1~", nil, Spec)
+	if n, err = cB.Run(100); err != ErrMaxSteps || n != 100 || cB.Steps() != 100 {
+		t.Errorf("got %d, %v after %d steps, want 100, ErrMaxSteps", n, err, cB.Steps())
+	}
+	if n, err = cB.Run(2); err != ErrMaxSteps || n != 2 {
+		t.Errorf("got %d, %v resuming, want 2, ErrMaxSteps", n, err)
+	}
+}
+
+func TestInspector(t *testing.T) {
+	var i Inspector = NewCodeBox("1v\n ;", nil, Spec)
+	for n := 0; n < 2; n++ {
+		if _, err := i.(Stepper).Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if x, y := i.Position(); x != 1 || y != 1 {
+		t.Errorf("got position (%d,%d), want (1,1)", x, y)
+	}
+	if d := i.Direction(); d != Down {
+		t.Errorf("got direction %v, want %v", d, Down)
+	}
+	if n := i.Steps(); n != 2 {
+		t.Errorf("got %d steps, want 2", n)
+	}
+	if s := i.Stack(); len(s) != 1 || s[0] != 1 {
+		t.Errorf("got stack %v, want [1]", s)
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if n, err := NewCodeBox("1~", nil, Spec).RunContext(ctx, 0); err != context.Canceled || n != 0 {
+		t.Errorf("got %d, %v, want 0, context.Canceled", n, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := NewCodeBox("1~", nil, Spec).RunContext(ctx, 0); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	cB := NewCodeBox("'hi'oo1", nil, Spec, WithOutput(w))
+	if _, err := cB.Run(6); err != ErrMaxSteps {
+		t.Fatalf("got %v, want ErrMaxSteps", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("output %q was written before flushing", buf.String())
+	}
+	if err := cB.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "ih" {
+		t.Errorf("got output %q, want %q", buf.String(), "ih")
+	}
+	if _, err := cB.Swim(); err != ErrClosed {
+		t.Errorf("Swim got %v, want ErrClosed", err)
+	}
+	if n, err := cB.Run(0); err != ErrClosed || n != 0 {
+		t.Errorf("Run got %d, %v, want 0, ErrClosed", n, err)
+	}
+	if err := cB.Close(); err != ErrClosed {
+		t.Errorf("Close got %v, want ErrClosed", err)
+	}
+	if len(cB.Stack()) != 0 || cB.box != nil {
+		t.Errorf("the stack %v and codebox weren't released", cB.Stack())
+	}
+}
@@ -0,0 +1,44 @@
+package fish
+
+import (
+	"io"
+	"strings"
+)
+
+// LineInput is an input adapter for feeding a ><> a line at a time, such as when testing an interactive
+// program. Give it to a CodeBox with WithInput, and queue lines with Queue, even once the ><> is running.
+// Each line is ended with Newline, replacing any line ending it already had, and once the queued lines run
+// out EOF is sent, if it isn't empty, before "i" starts pushing -1.
+type LineInput struct {
+	Newline string
+	EOF     string
+	pending string
+	sentEOF bool
+}
+
+// NewLineInput returns a pointer to a LineInput which ends lines with "\n", with the given lines queued.
+func NewLineInput(lines ...string) *LineInput {
+	l := &LineInput{Newline: "\n"}
+	l.Queue(lines...)
+	return l
+}
+
+// Queue adds lines to the end of the input.
+func (l *LineInput) Queue(lines ...string) {
+	for _, line := range lines {
+		l.pending += strings.TrimRight(line, "\r\n") + l.Newline
+	}
+}
+
+// Read implements io.Reader.
+func (l *LineInput) Read(p []byte) (int, error) {
+	if l.pending == "" && !l.sentEOF {
+		l.pending, l.sentEOF = l.EOF, true
+	}
+	if l.pending == "" {
+		return 0, io.EOF
+	}
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,35 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestLineInput(t *testing.T) {
+	in := NewLineInput("ab\r\n")
+	in.Newline, in.EOF = "\r\n", "\x04"
+	in.Queue("c")
+	cB := NewCodeBox("iiiiiiiiii;", []float64{}, Spec, WithInput(in))
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{'a', 'b', '\n', 'c', '\r', '\n', 4, -1, -1, -1}
+	if s := cB.Stack(); !sameValues(s, want) {
+		t.Errorf("got stack %v, want %v", s, want)
+	}
+}
+
+func TestLineInputQueue(t *testing.T) {
+	in := NewLineInput("1")
+	cB := NewCodeBox("iiiiii;", []float64{}, Spec, WithInput(in))
+	if steps, _ := cB.Run(2); steps != 2 {
+		t.Fatalf("got %d steps, want 2", steps)
+	}
+	in.Queue("23")
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{'1', '\n', '2', '3', '\n', -1}
+	if s := cB.Stack(); !sameValues(s, want) {
+		t.Errorf("got stack %v, want %v", s, want)
+	}
+}
@@ -0,0 +1,82 @@
+package fish
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Capability is a resource outside the ><>'s own state which an extension may use.
+type Capability string
+
+const (
+	Filesystem Capability = "filesystem" // Reading or writing files
+	Network    Capability = "network"    // Making or accepting connections
+	Clock      Capability = "clock"      // Reading the time, which makes a run depend on when it happens
+)
+
+// Manifest declares what an extension provides and what it needs, so a host can decide whether to load it.
+type Manifest struct {
+	Name  string
+	Ops   string       // The instructions the extension provides, in any order
+	Needs []Capability // Nil if the extension only uses the ><>'s stacks, codebox, input and output
+}
+
+// Declarer is implemented by extensions which declare a Manifest. Every extension in this package does, and
+// hosts loading third party extensions should require it with Approve.
+type Declarer interface {
+	Manifest() Manifest
+}
+
+var (
+	// ErrUndeclared is returned by Approve for an extension without a truthful Manifest.
+	ErrUndeclared = errors.New("extension has no manifest")
+	// ErrDenied is returned by Approve for an extension needing a capability the host didn't allow.
+	ErrDenied = errors.New("capability denied")
+)
+
+// Approve returns the Manifest of ext if ext declares one which matches the instructions it provides, and
+// needs only capabilities in allowed. Otherwise it returns an error wrapping ErrUndeclared or ErrDenied.
+//
+// The Manifest is declared by ext itself, so Approve can only check that it's consistent, not that it's
+// true: nothing stops an extension using a capability it didn't declare. Nor can it run before ext exists,
+// by which time a plugin has been opened, or a Process started. Only approve extensions you trust.
+func Approve(ext Extension, allowed ...Capability) (Manifest, error) {
+	d, ok := ext.(Declarer)
+	if !ok {
+		return Manifest{}, fmt.Errorf("%w: %T", ErrUndeclared, ext)
+	}
+	m := d.Manifest()
+	if ops := extensionOps(ext); ops != sortOps(m.Ops) {
+		return m, fmt.Errorf("%w: %s declares %q but provides %q", ErrUndeclared, m.Name, m.Ops, ops)
+	}
+	var denied []string
+	for _, c := range m.Needs {
+		ok := false
+		for _, a := range allowed {
+			ok = ok || a == c
+		}
+		if !ok {
+			denied = append(denied, string(c))
+		}
+	}
+	if len(denied) > 0 {
+		return m, fmt.Errorf("%w: %s needs %s", ErrDenied, m.Name, strings.Join(denied, ", "))
+	}
+	return m, nil
+}
+
+// ParseCapabilities parses a comma separated list of capabilities, such as "filesystem,clock".
+func ParseCapabilities(s string) ([]Capability, error) {
+	var cs []Capability
+	for _, name := range strings.Split(s, ",") {
+		switch c := Capability(strings.TrimSpace(name)); c {
+		case "":
+		case Filesystem, Network, Clock:
+			cs = append(cs, c)
+		default:
+			return nil, fmt.Errorf("unknown capability %q", name)
+		}
+	}
+	return cs, nil
+}
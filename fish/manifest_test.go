@@ -0,0 +1,83 @@
+package fish
+
+import (
+	"errors"
+	"testing"
+)
+
+// liar declares fewer instructions than it provides.
+type liar struct{}
+
+func (liar) Instructions() map[byte]Instruction {
+	return map[byte]Instruction{'X': func(*CodeBox) {}, 'Y': func(*CodeBox) {}}
+}
+
+func (liar) Manifest() Manifest {
+	return Manifest{Name: "liar", Ops: "X"}
+}
+
+// spy needs the network.
+type spy struct{}
+
+func (spy) Instructions() map[byte]Instruction {
+	return map[byte]Instruction{'N': func(*CodeBox) {}}
+}
+
+func (spy) Manifest() Manifest {
+	return Manifest{Name: "spy", Ops: "N", Needs: []Capability{Network, Clock}}
+}
+
+// shuffled declares its instructions out of order.
+type shuffled struct{}
+
+func (shuffled) Instructions() map[byte]Instruction {
+	return map[byte]Instruction{'X': func(*CodeBox) {}, 'Y': func(*CodeBox) {}}
+}
+
+func (shuffled) Manifest() Manifest {
+	return Manifest{Name: "shuffled", Ops: "YX"}
+}
+
+func TestApprove(t *testing.T) {
+	for _, ext := range []Extension{AssertExtension{}, CallExtension{}, DebugExtension{}, NumberInput{},
+		&StoreExtension{}, &StoreExtension{ReadOnly: true}, &Tone{}} {
+		if _, err := Approve(ext); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := Approve(Timing{}); !errors.Is(err, ErrDenied) {
+		t.Errorf("got %v, want ErrDenied", err)
+	}
+	if _, err := Approve(Timing{}, Clock); err != nil {
+		t.Error(err)
+	}
+	if _, err := Approve(liar{}); !errors.Is(err, ErrUndeclared) {
+		t.Errorf("got %v, want ErrUndeclared", err)
+	}
+	if _, err := Approve(leakyExtension{}); !errors.Is(err, ErrUndeclared) {
+		t.Errorf("got %v, want ErrUndeclared", err)
+	}
+	_, err := Approve(spy{}, Clock)
+	if want := "capability denied: spy needs network"; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %q", err, want)
+	}
+	if _, err := Approve(spy{}, Network, Clock); err != nil {
+		t.Error(err)
+	}
+	if _, err := Approve(shuffled{}); err != nil {
+		t.Errorf("got %v for instructions declared out of order", err)
+	}
+}
+
+func TestParseCapabilities(t *testing.T) {
+	cs, err := ParseCapabilities("filesystem, clock")
+	if err != nil || len(cs) != 2 || cs[0] != Filesystem || cs[1] != Clock {
+		t.Errorf("got %v, %v", cs, err)
+	}
+	if cs, err := ParseCapabilities(""); err != nil || len(cs) != 0 {
+		t.Errorf("got %v, %v", cs, err)
+	}
+	if _, err := ParseCapabilities("gpu"); err == nil {
+		t.Error("parsed an unknown capability")
+	}
+}
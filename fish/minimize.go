@@ -0,0 +1,110 @@
+package fish
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strings"
+)
+
+// failure returns a description of the panic value r which doesn't depend on where it happened, so
+// failures can be compared as a program shrinks.
+func failure(r interface{}) string {
+	switch r := r.(type) {
+	case *ErrInvalidInstruction:
+		return fmt.Sprintf("invalid instruction %q", r.Op)
+	case runtime.Error:
+		msg := r.Error()
+		if i := strings.IndexAny(msg, "[0123456789"); i != -1 {
+			msg = msg[:i]
+		}
+		return msg
+	}
+	return fmt.Sprint(r)
+}
+
+// try runs script deterministically with input for at most maxSteps steps, and returns a description of
+// how it failed, or "" if it didn't.
+func try(script string, input []byte, maxSteps int) (fail string) {
+	if strings.TrimSpace(script) == "" {
+		return ""
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			fail = failure(r)
+		}
+	}()
+	cB := NewCodeBox(script, nil, Spec, WithInput(bytes.NewReader(input)), WithDeterministic(),
+		WithOutput(ioutil.Discard))
+	for i := 0; i < maxSteps && !cB.swim(); i++ {
+	}
+	return ""
+}
+
+// Minimize shrinks a script and input which make the interpreter fail, returning the smallest ones it
+// finds which still fail the same way. Each attempt is run deterministically for at most maxSteps steps.
+// If script and input don't fail to begin with, they are returned unchanged.
+func Minimize(script string, input []byte, maxSteps int) (string, []byte) {
+	want := try(script, input, maxSteps)
+	if want == "" {
+		return script, input
+	}
+	lines := splitScript(script)
+	fails := func(lines []string, input []byte) bool {
+		return try(strings.Join(lines, "\n"), input, maxSteps) == want
+	}
+
+	for changed := true; changed; {
+		changed = false
+		// Remove rows.
+		for y := 0; y < len(lines) && len(lines) > 1; y++ {
+			if l := append(append([]string{}, lines[:y]...), lines[y+1:]...); fails(l, input) {
+				lines, changed = l, true
+				y--
+			}
+		}
+		// Remove columns.
+		for x := 0; x < longestLineLength(lines); x++ {
+			l := make([]string, len(lines))
+			for y, line := range lines {
+				if x < len(line) {
+					line = line[:x] + line[x+1:]
+				}
+				l[y] = line
+			}
+			if fails(l, input) {
+				lines, changed = l, true
+				x--
+			}
+		}
+		// Blank out cells.
+		for y := range lines {
+			for x := range lines[y] {
+				if lines[y][x] == ' ' {
+					continue
+				}
+				l := append([]string{}, lines...)
+				l[y] = l[y][:x] + " " + l[y][x+1:]
+				if fails(l, input) {
+					lines, changed = l, true
+				}
+			}
+		}
+		// Remove chunks of input, halving their size down to single bytes.
+		for n := len(input); n > 0; n /= 2 {
+			for i := 0; i+n <= len(input); {
+				if in := append(append([]byte{}, input[:i]...), input[i+n:]...); fails(lines, in) {
+					input, changed = in, true
+				} else {
+					i += n
+				}
+			}
+		}
+	}
+
+	for y := range lines {
+		lines[y] = strings.TrimRight(lines[y], " ")
+	}
+	return strings.Join(lines, "\n"), input
+}
@@ -0,0 +1,26 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestMinimize(t *testing.T) {
+	script, input := Minimize("1 2 3 +++~;", nil, 100)
+	if len(script) != 1 || len(input) != 0 || try(script, input, 100) != "Stack is empty!" {
+		t.Errorf("got %q, %q", script, input)
+	}
+
+	script, input = Minimize("v\n>i:'q'=?X~", []byte("abcdq123"), 1000)
+	if script != "X" || len(input) != 0 {
+		t.Errorf("got %q, %q", script, input)
+	}
+
+	script, input = Minimize("v\n>i:0(?;'a'=?Xv\n^            <", []byte("zzazz"), 1000)
+	if try(script, input, 1000) != `invalid instruction 'X'` || len(input) != 0 {
+		t.Errorf("got %q, %q", script, input)
+	}
+
+	if script, input = Minimize("1~;", []byte("a"), 100); script != "1~;" || string(input) != "a" {
+		t.Fail()
+	}
+}
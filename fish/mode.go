@@ -0,0 +1,43 @@
+package fish
+
+import (
+	"fmt"
+)
+
+// Mode is the behaviour a CodeBox follows where ><> interpreters disagree.
+type Mode byte
+
+const (
+	// Spec follows the esolangs.org specification. "[" and "]" move values without reordering them,
+	// negative coordinates are an error, "g" pushes empty cells as spaces, and "%" works on integers.
+	Spec Mode = iota
+	// FishLanguageCom behaves like the fishlanguage.com interpreter. "[" and "]" reverse the values they
+	// move, "p" grows the codebox up or left for negative coordinates, "g" pushes empty cells as 0, and "%"
+	// is JavaScript's floating point remainder, which takes the sign of the dividend.
+	FishLanguageCom
+	// FishInterpreterCom behaves like the fishinterpreter.com interpreter. "[" and "]" move values without
+	// reordering them, "p" grows the codebox up or left for negative coordinates, "g" pushes empty cells as
+	// 0, and "%" is a floating point remainder which takes the sign of the divisor, as in Python.
+	FishInterpreterCom
+)
+
+// modeNames are the names of the modes, as used by String and ParseMode.
+var modeNames = []string{"spec", "fishlanguage.com", "fishinterpreter.com"}
+
+func (m Mode) String() string {
+	if int(m) < len(modeNames) {
+		return modeNames[m]
+	}
+	return fmt.Sprintf("Mode(%d)", m)
+}
+
+// ParseMode returns the Mode named name, which is one of "spec", "fishlanguage.com" or
+// "fishinterpreter.com".
+func ParseMode(name string) (Mode, error) {
+	for i, n := range modeNames {
+		if n == name {
+			return Mode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mode %q", name)
+}
@@ -0,0 +1,48 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestModes(t *testing.T) {
+	tests := []struct {
+		script string
+		stack  []float64
+		want   [3][]float64 // For Spec, FishLanguageCom and FishInterpreterCom
+	}{
+		{"3%;", []float64{-7}, [3][]float64{{-1}, {-1}, {2}}},
+		{"2%;", []float64{5.5}, [3][]float64{{1}, {1.5}, {1.5}}},
+		{"2[;", []float64{1, 2}, [3][]float64{{1, 2}, {2, 1}, {1, 2}}},
+		{"30g ;", nil, [3][]float64{{' '}, {0}, {0}}},
+	}
+	for _, test := range tests {
+		for i, mode := range []Mode{Spec, FishLanguageCom, FishInterpreterCom} {
+			cB := NewCodeBox(test.script, test.stack, mode)
+			if _, err := cB.Run(0); err != nil {
+				t.Errorf("%q in %v mode: %v", test.script, mode, err)
+			} else if s := cB.Stack(); !sameValues(s, test.want[i]) {
+				t.Errorf("%q in %v mode: got stack %v, want %v", test.script, mode, s, test.want[i])
+			}
+		}
+	}
+
+	if _, err := NewCodeBox("1001-p;", nil, Spec).Run(0); err == nil {
+		t.Error("spec mode wrote to a negative coordinate")
+	}
+	for _, mode := range []Mode{FishLanguageCom, FishInterpreterCom} {
+		if _, err := NewCodeBox("1001-p;", nil, mode).Run(0); err != nil {
+			t.Errorf("%v mode: %v", mode, err)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, mode := range []Mode{Spec, FishLanguageCom, FishInterpreterCom} {
+		if m, err := ParseMode(mode.String()); err != nil || m != mode {
+			t.Errorf("got %v, %v for %q, want %v", m, err, mode.String(), mode)
+		}
+	}
+	if _, err := ParseMode("normal"); err == nil {
+		t.Error("parsed an unknown mode")
+	}
+}
@@ -0,0 +1,79 @@
+package fish
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WithNarration causes a natural-language description of each step, such as "push 7" or "mirror: now
+// moving Up", to be written to w as the ><> swims.
+func WithNarration(w io.Writer) Option {
+	return func(cB *CodeBox) {
+		cB.narration = w
+	}
+}
+
+func formatStack(s []float64) string {
+	if len(s) == 0 {
+		return "empty"
+	}
+	strs := make([]string, len(s))
+	for i, v := range s {
+		strs[i] = fmt.Sprint(v)
+	}
+	return strings.Join(strs, " ")
+}
+
+// narrate describes the execution of r at (x,y), which began with the ><> swimming in d with the stack
+// before. str is set if r was pushed in string mode.
+func (cB *CodeBox) narrate(x, y int, d Direction, r byte, str bool, before []float64) {
+	if cB.narration == nil {
+		return
+	}
+	var msg string
+	now := formatStack(cB.Stack())
+	switch {
+	case str:
+		msg = fmt.Sprintf("push %d (%q)", r, r)
+	default:
+		if desc, ok := descriptions[r]; ok {
+			msg = fmt.Sprintf("%s (now %s)", desc, now)
+		} else {
+			msg = fmt.Sprintf("extension instruction %q (now %s)", r, now)
+		}
+	case strings.IndexByte("0123456789abcdef", r) != -1:
+		msg = fmt.Sprintf("push %v", cB.Stack()[len(cB.Stack())-1])
+	case r == ' ':
+		msg = "swim through empty water"
+	case r == ';':
+		msg = "halt"
+	case r == ':':
+		msg = fmt.Sprintf("duplicate top (now %s)", now)
+	case strings.IndexByte("><^vx", r) != -1:
+		msg = fmt.Sprintf("now moving %v", cB.fDir)
+	case strings.IndexByte("|_#/\\", r) != -1:
+		if cB.fDir != d {
+			msg = fmt.Sprintf("mirror: now moving %v", cB.fDir)
+		} else {
+			msg = fmt.Sprintf("mirror: still moving %v", cB.fDir)
+		}
+	case r == '"' || r == '\'':
+		if cB.stringMode != 0 {
+			msg = "enter string mode"
+		} else {
+			msg = "leave string mode"
+		}
+	case r == '!':
+		msg = "trampoline: skip the next instruction"
+	case r == '?':
+		if before[len(before)-1] == 0 {
+			msg = "pop 0: skip the next instruction"
+		} else {
+			msg = fmt.Sprintf("pop %v: execute the next instruction", before[len(before)-1])
+		}
+	case r == '.':
+		msg = fmt.Sprintf("jump to (%d,%d)", cB.fX, cB.fY)
+	}
+	fmt.Fprintf(cB.narration, "(%d,%d) %s\n", x, y, msg)
+}
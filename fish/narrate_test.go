@@ -0,0 +1,41 @@
+package fish
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWithNarration(t *testing.T) {
+	buf := new(bytes.Buffer)
+	cB := NewCodeBox("7:/\n  ;", []float64{}, Spec, WithNarration(buf))
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	want := "(0,0) push 7\n(1,0) duplicate top (now 7 7)\n(2,0) mirror: now moving Up\n(2,1) halt\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	cB = NewCodeBox(`"a";`, []float64{}, Spec, WithNarration(buf))
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	want = "(0,0) enter string mode\n(1,0) push 97 ('a')\n(2,0) leave string mode\n(3,0) halt\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	cB = NewCodeBox("?;1n;", []float64{}, Spec, WithNarration(buf), OnUnderflow(func(byte) (float64, bool) {
+		return 0, true
+	}))
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	want = "(0,0) pop 0: skip the next instruction\n(2,0) push 1\n" +
+		"(3,0) pops a value and outputs it as a number (now empty)\n(4,0) halt\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
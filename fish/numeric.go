@@ -0,0 +1,114 @@
+package fish
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// withRound causes every value pushed, and every value on the initial stack, to be rounded with f, after
+// any rounding added by earlier options.
+func withRound(f func(float64) float64) Option {
+	return func(cB *CodeBox) {
+		if prev := cB.round; prev != nil {
+			cB.round = func(v float64) float64 {
+				return f(prev(v))
+			}
+		} else {
+			cB.round = f
+		}
+		for i, v := range cB.stacks[0].S {
+			cB.stacks[0].S[i] = f(v)
+		}
+	}
+}
+
+// WithFloat32 makes the stack behave as though it held float32 values rather than float64 values.
+func WithFloat32() Option {
+	return withRound(func(v float64) float64 {
+		return float64(float32(v))
+	})
+}
+
+// WithPrecision makes the stack behave as though its values had a mantissa of only bits bits, rounding to
+// nearest even. bits must be between 1 and 53, as values are still stored as float64; otherwise the
+// CodeBox's Err reports ErrOutOfRange.
+//
+// There is no big.Float backend for more precision than float64 has: the stacks, the codebox and every
+// instruction, extension and trace work with float64, so WithPrecision can only simulate narrower values.
+func WithPrecision(bits uint) Option {
+	if bits < 1 || bits > 53 {
+		return func(cB *CodeBox) {
+			cB.invalid(fmt.Errorf("%w: precision must be between 1 and 53 bits, not %d", ErrOutOfRange, bits))
+		}
+	}
+	return withRound(func(v float64) float64 {
+		f, _ := new(big.Float).SetPrec(bits).SetMode(big.ToNearestEven).SetFloat64(v).Float64()
+		return f
+	})
+}
+
+// Division is how "," and "%" behave in integer-only mode.
+type Division byte
+
+const (
+	Truncate Division = iota // Round quotients toward zero, as Go does
+	Floor                    // Round quotients toward negative infinity, as Python does
+	Exact                    // Quotients with a remainder are an error
+)
+
+// WithIntegers makes the ><> integer-only: "," and "%" follow div, and pushing any other fractional value
+// fails with ErrFractional. If one is on the initial stack, the CodeBox's Err reports it.
+func WithIntegers(div Division) Option {
+	return func(cB *CodeBox) {
+		cB.division = &div
+		for _, v := range cB.stacks[0].S {
+			if v != math.Trunc(v) {
+				cB.invalid(fmt.Errorf("%w: %v is on the initial stack", ErrFractional, v))
+				return
+			}
+		}
+		withRound(func(v float64) float64 {
+			if v != math.Trunc(v) {
+				panic(failf(ErrFractional, "Fractional value %v in integer-only mode!", v))
+			}
+			return v
+		})(cB)
+	}
+}
+
+// divide implements ",".
+func (cB *CodeBox) divide(y, x float64) float64 {
+	if x == 0 {
+		panic(ErrDivisionByZero)
+	} else if cB.division == nil {
+		return y / x
+	}
+	switch *cB.division {
+	case Floor:
+		return math.Floor(y / x)
+	case Exact:
+		if math.Mod(y, x) != 0 {
+			panic(failf(ErrNotDivisible, "%v is not divisible by %v!", y, x))
+		}
+	}
+	return math.Trunc(y / x)
+}
+
+// modulo implements "%".
+func (cB *CodeBox) modulo(y, x float64) float64 {
+	if x == 0 {
+		panic(ErrDivisionByZero)
+	} else if cB.division != nil && *cB.division == Floor {
+		return y - x*math.Floor(y/x)
+	} else if cB.mode == FishLanguageCom {
+		return math.Mod(y, x) // fishlanguage.com uses JavaScript's floating point %
+	} else if cB.mode == FishInterpreterCom {
+		return y - x*math.Floor(y/x)
+	}
+	divisor := toInt(x, "Divisor")
+	if divisor == 0 {
+		panic(ErrDivisionByZero)
+	}
+	return float64(toInt(y, "Dividend") % divisor)
+}
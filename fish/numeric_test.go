@@ -0,0 +1,92 @@
+package fish
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestWithFloat32(t *testing.T) {
+	cB := NewCodeBox("13,;", []float64{0.1}, Spec, WithFloat32())
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	s := cB.Stack()
+	if s[0] != float64(float32(0.1)) || s[1] != float64(float32(1.0/3)) {
+		t.Fail()
+	}
+
+	cB = NewCodeBox("f:*:*:*:*:*:*:*:*:*;", []float64{}, Spec, WithFloat32())
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(cB.Pop(), 1) {
+		t.Fail()
+	}
+}
+
+func TestWithPrecision(t *testing.T) {
+	cB := NewCodeBox("13,;", []float64{}, Spec, WithPrecision(4))
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if v := cB.Pop(); v != 0.34375 { // 0.01011 in binary, rounded to 4 significant bits
+		t.Error(v)
+	}
+
+	cB = NewCodeBox("1;", []float64{}, Spec, WithPrecision(64))
+	if _, err := cB.Run(0); !errors.Is(err, ErrOutOfRange) || cB.Err() != err {
+		t.Errorf("got %v for 64 bits, want ErrOutOfRange", err)
+	}
+}
+
+// swimAll runs cB until it halts, and returns a description of how it failed, or "" if it didn't.
+func swimAll(cB *CodeBox) (fail string) {
+	defer func() {
+		if r := recover(); r != nil {
+			fail = failure(r)
+		}
+	}()
+	for !cB.swim() {
+	}
+	return ""
+}
+
+func TestWithIntegers(t *testing.T) {
+	tests := []struct {
+		div  Division
+		want []float64
+	}{
+		{Truncate, []float64{-2, -1}},
+		{Floor, []float64{-3, 1}},
+		{Exact, nil},
+	}
+	for _, test := range tests {
+		cB := NewCodeBox("27-2,27-2%;", []float64{}, Spec, WithIntegers(test.div))
+		fail := swimAll(cB)
+		if test.want == nil {
+			if fail == "" {
+				t.Errorf("%d: expected failure", test.div)
+			}
+			continue
+		}
+		s := cB.Stack()
+		if fail != "" || len(s) != 2 || s[0] != test.want[0] || s[1] != test.want[1] {
+			t.Errorf("%d: got %v, want %v (%s)", test.div, s, test.want, fail)
+		}
+	}
+
+	cB := NewCodeBox("12,;", []float64{}, Spec, WithFloat32(), WithIntegers(Truncate))
+	if swimAll(cB) != "" || cB.Pop() != 0 {
+		t.Fail()
+	}
+	if swimAll(NewCodeBox("10,;", []float64{}, Spec, WithIntegers(Floor))) != "Division by zero!" {
+		t.Fail()
+	}
+	if _, err := NewCodeBox("32,;", nil, Spec, WithIntegers(Exact)).Run(0); !errors.Is(err, ErrNotDivisible) {
+		t.Errorf("got %v, want ErrNotDivisible", err)
+	}
+	if err := NewCodeBox(";", []float64{0.5}, Spec, WithIntegers(Floor)).Err(); !errors.Is(err, ErrFractional) {
+		t.Errorf("got %v for a fractional initial stack, want ErrFractional", err)
+	}
+}
@@ -0,0 +1,27 @@
+package fish
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNumberInput(t *testing.T) {
+	var log EventLog
+	cB := NewCodeBox("IIIII;", []float64{}, Spec, WithExtension(NumberInput{}),
+		WithInput(strings.NewReader("ab 12, -3.5x- 1.\n-7")), WithEventLog(&log))
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{12, 1, -3.5, 1, 1, 1, -7, 1, 0}
+	if s := cB.Stack(); !sameValues(s, want) {
+		t.Errorf("got stack %v, want %v", s, want)
+	}
+
+	cB = NewCodeBox("IIIII;", []float64{}, Spec, WithExtension(NumberInput{}), log.Replay())
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if s := cB.Stack(); !sameValues(s, want) {
+		t.Errorf("replayed, got stack %v, want %v", s, want)
+	}
+}
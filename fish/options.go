@@ -0,0 +1,89 @@
+package fish
+
+import (
+	"bufio"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+// Option configures a CodeBox, and is passed to NewCodeBox.
+type Option func(cB *CodeBox)
+
+// WithInput causes "i" to read from r instead of stdin. Without it, each CodeBox reads stdin through its own
+// buffer, so a host running several CodeBoxes at once should give each of them its own input.
+func WithInput(r io.Reader) Option {
+	return func(cB *CodeBox) {
+		cB.input = bufio.NewReader(r)
+	}
+}
+
+// WithDeterministic guarantees that a given script and input always produce the same execution. "x" uses a
+// fixed seed, CodeBox.Now always returns the Unix epoch, CodeBox.Sleep returns immediately, and "i" never reads
+// stdin: unless WithInput is also used, the ><> receives no input.
+func WithDeterministic() Option {
+	return func(cB *CodeBox) {
+		cB.deterministic = true
+		cB.rand = rand.New(rand.NewSource(0))
+		cB.now = func() time.Time {
+			return time.Unix(0, 0).UTC()
+		}
+		if cB.input == nil {
+			cB.input = bufio.NewReader(strings.NewReader(""))
+		}
+	}
+}
+
+// WithRand makes "x" draw its directions from src, which may be a *rand.Rand, instead of the global source
+// seeded with the time, so a host can reproduce or replay runs.
+func WithRand(src rand.Source) Option {
+	return func(cB *CodeBox) {
+		if r, ok := src.(*rand.Rand); ok {
+			cB.rand = r
+		} else {
+			cB.rand = rand.New(src)
+		}
+	}
+}
+
+// WithFastStrings pushes the whole of a string, up to its closing quote, in a single Swim instead of one
+// character per Swim. Anything which counts steps, such as observers, quotas and watches, sees the string
+// as one step. It has no effect while narrating.
+func WithFastStrings() Option {
+	return func(cB *CodeBox) {
+		cB.fastStrings = true
+	}
+}
+
+// WithOutput sends the ><>'s output, from "o" and "n", to w instead of stdout.
+func WithOutput(w io.Writer) Option {
+	return func(cB *CodeBox) {
+		cB.out = w
+	}
+}
+
+// stdout returns the writer for the ><>'s output.
+func (cB *CodeBox) stdout() io.Writer {
+	if cB.out != nil {
+		return cB.out
+	}
+	return os.Stdout
+}
+
+// WithDiagnostics sends human-facing messages, such as PrintBox's output and crash reports, to w instead of
+// stderr, keeping them apart from the ><>'s output.
+func WithDiagnostics(w io.Writer) Option {
+	return func(cB *CodeBox) {
+		cB.diagnostics = w
+	}
+}
+
+// diag returns the writer for diagnostics.
+func (cB *CodeBox) diag() io.Writer {
+	if cB.diagnostics != nil {
+		return cB.diagnostics
+	}
+	return os.Stderr
+}
@@ -0,0 +1,141 @@
+package fish
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithInput(t *testing.T) {
+	cB := NewCodeBox("iii;", []float64{}, Spec, WithInput(strings.NewReader("ab")))
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	s := cB.Stack()
+	if len(s) != 3 || s[0] != 'a' || s[1] != 'b' || s[2] != -1 {
+		t.FailNow()
+	}
+}
+
+func TestStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+	os.Stdin = r
+	a, b := NewCodeBox("i;", nil, Spec), NewCodeBox("i;", nil, Spec)
+	w.WriteString("x")
+	w.Close()
+	for _, cB := range []*CodeBox{a, b} {
+		if _, err := cB.Run(0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only one CodeBox can see each byte of stdin, and nothing else may consume it.
+	if a.Stack()[0] != 'x' || b.Stack()[0] != -1 {
+		t.Errorf("got %v and %v, want [%d] and [-1]", a.Stack(), b.Stack(), 'x')
+	}
+}
+
+func TestWithDeterministic(t *testing.T) {
+	var paths [2][]Direction
+	for i := range paths {
+		cB := NewCodeBox("x", []float64{}, Spec, WithDeterministic())
+		for ii := 0; ii < 20; ii++ {
+			cB.Swim()
+			paths[i] = append(paths[i], cB.fDir)
+		}
+		if !cB.Now().Equal(time.Unix(0, 0)) {
+			t.FailNow()
+		}
+	}
+	for i := range paths[0] {
+		if paths[0][i] != paths[1][i] {
+			t.FailNow()
+		}
+	}
+
+	cB := NewCodeBox("i;", []float64{}, Spec, WithDeterministic())
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if cB.Pop() != -1 {
+		t.Fail()
+	}
+}
+
+func TestWithRand(t *testing.T) {
+	var paths [2][]Direction
+	for i := range paths {
+		var src rand.Source = rand.NewSource(42)
+		if i == 1 {
+			src = rand.New(rand.NewSource(42))
+		}
+		cB := NewCodeBox("x", []float64{}, Spec, WithRand(src))
+		for ii := 0; ii < 20; ii++ {
+			cB.Swim()
+			paths[i] = append(paths[i], cB.fDir)
+		}
+	}
+	for i := range paths[0] {
+		if paths[0][i] != paths[1][i] {
+			t.Fatalf("got paths %v and %v from the same seed", paths[0], paths[1])
+		}
+	}
+}
+
+func TestWithFastStrings(t *testing.T) {
+	for _, script := range []string{
+		`"hello"rooooo;`,
+		"v\n\"\na\nb\n\"\n;",
+		`"ab;`,
+		`'"' "'" ;`,
+		"v\n'\na\n;\nb\nc",
+	} {
+		slow := NewCodeBox(script, nil, Spec)
+		fast := NewCodeBox(script, nil, Spec, WithFastStrings())
+		if fail, fail2 := swimAll(slow), swimAll(fast); fail != fail2 || !sameValues(fast.Stack(), slow.Stack()) {
+			t.Errorf("%q: got %v %q, want %v %q", script, fast.Stack(), fail2, slow.Stack(), fail)
+		}
+		if fast.steps > slow.steps {
+			t.Errorf("%q: took %d steps, but %d without the fast path", script, fast.steps, slow.steps)
+		}
+	}
+
+	cB := NewCodeBox(`"hello";`, nil, Spec, WithFastStrings())
+	cB.swim()
+	cB.swim()
+	if cB.fX != 6 || len(cB.Stack()) != 5 {
+		t.Errorf("got %v at %d, want the string pushed in one step", cB.Stack(), cB.fX)
+	}
+}
+
+func TestWithDiagnostics(t *testing.T) {
+	buf := new(bytes.Buffer)
+	cB := NewCodeBox("1;", nil, Spec, WithDiagnostics(buf))
+	cB.PrintBox()
+	if want := "\n*1* ; \n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf, want)
+	}
+	if NewCodeBox("1;", nil, Spec).diag() != os.Stderr {
+		t.Error("diagnostics should default to stderr")
+	}
+}
+
+func TestWithOutput(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if _, err := NewCodeBox(`"hi"oo32,n;`, nil, Spec, WithOutput(buf)).Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if want := "ih1.5"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf, want)
+	}
+	if NewCodeBox("1;", nil, Spec).stdout() != os.Stdout {
+		t.Error("output should default to stdout")
+	}
+}
@@ -0,0 +1,137 @@
+package fish
+
+import (
+	"math"
+	"strings"
+)
+
+// pure holds the instructions whose effect depends only on the ><>'s state, so they can be executed ahead
+// of time.
+const pure = " ><^v/\\|_#!?.:~$@{}rl&g+-*,%=()0123456789abcdef'\""
+
+// pushValue returns ><> code which pushes the integer v.
+func pushValue(v int) string {
+	if v < 0 {
+		return "0" + pushInt(-v) + "-"
+	}
+	return pushInt(v)
+}
+
+// bakeable returns true if every value in s is an integer which pushValue can push.
+func bakeable(s []float64) bool {
+	for _, v := range s {
+		if v != math.Trunc(v) || math.Abs(v) > math.MaxInt32 {
+			return false
+		}
+	}
+	return true
+}
+
+// PartialEvaluate executes the deterministic, input-free prefix of script ahead of time, for at most
+// maxSteps steps, and returns a residual script which starts with the resulting stack and register already
+// in place. The residual script has a new first row which sets them up and jumps into the rest of the
+// script, which is moved down with Relocate, and any warnings from Relocate are returned. It also returns
+// the number of steps evaluated, which is 0 if no prefix could be evaluated, in which case script is
+// returned unchanged.
+//
+// The prefix ends before the first instruction which does I/O, uses randomness, modifies the codebox,
+// halts, fails, or isn't built in. The residual script must be started with an empty stack, and its first
+// row may be wider than script. As the new first row would be in the way of a ><> wrapping vertically
+// through it, nothing is evaluated if the ><> may do so; a Warning at the cell it wraps from says why.
+func PartialEvaluate(script string, maxSteps int) (string, int, []Warning, error) {
+	// The coordinates of a Reference may be rewritten as several instructions ahead of the first, so the
+	// ><> can't resume partway through them
+	midway := make(map[[2]int]bool)
+	for _, ref := range NewCodeBox(script, nil, Spec).References() {
+		if ref.Static {
+			midway[ref.CY], midway[[2]int{ref.X, ref.Y}] = true, true
+		}
+	}
+	cB := NewCodeBox(script, nil, Spec)
+	var (
+		steps, x, y int
+		stack       []float64
+		register    *float64
+	)
+	for cB.steps < maxSteps {
+		if cB.fX < 0 || cB.fY < 0 || cB.fX >= cB.width || cB.fY >= cB.height {
+			break // Jumped out of the codebox
+		}
+		r := cB.box[cB.fY][cB.fX]
+		s := cB.stacks[0]
+		if cB.stringMode == 0 && cB.fDir == Right && cB.fX > 0 && cB.p == 0 && bakeable(s.S) &&
+			!midway[[2]int{cB.fX, cB.fY}] &&
+			(!s.filledRegister || bakeable([]float64{s.register})) {
+			steps, x, y = cB.steps, cB.fX, cB.fY
+			stack, register = append(stack[:0], s.S...), nil
+			if s.filledRegister {
+				register = new(float64)
+				*register = s.register
+			}
+		}
+		if cB.stringMode == 0 && strings.IndexByte(pure, r) < 0 {
+			break
+		}
+		if done, fail := swimRecover(cB); done || fail != "" {
+			break
+		}
+	}
+	if steps == 0 {
+		return script, 0, nil, nil
+	}
+
+	var prefix string
+	if register != nil {
+		prefix = pushValue(int(*register)) + "&"
+	}
+	for _, v := range stack {
+		prefix += pushValue(int(v))
+	}
+	r, err := relocate(script, 0, 1, false)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	// The ><> moves right after jumping, so jump to the cell before (x,y), ahead of any cells inserted
+	// before it.
+	prefix += Jump(shift(r.cols, x)-r.cols[x]-1, 1+shift(r.rows, y))
+	if w, ok := wrapsThrough(NewCodeBox(script, nil, Spec), len(prefix)); ok {
+		return script, 0, []Warning{w}, nil
+	}
+	for i := range r.warnings {
+		r.warnings[i].Y++
+	}
+	return prefix + "\n" + strings.Join(r.lines, "\n"), steps, r.warnings, nil
+}
+
+// wrapsThrough returns a Warning if the ><> may wrap from the top row of the codebox to the bottom, or from
+// the bottom to the top, in one of the first width columns, which would take it through a row inserted
+// above the codebox.
+func wrapsThrough(cB *CodeBox, width int) (w Warning, found bool) {
+	cB.explore(func(s state, r byte) {
+		if found || s.x >= width {
+			return
+		}
+		dirs, n := []Direction{s.dir}, 1
+		if s.stringMode == 0 {
+			switch r {
+			case ';', '.':
+				return
+			case '>', 'v', '<', '^':
+				dirs[0] = Direction(strings.IndexByte(">v<^", r))
+			case '|', '_', '#', '/', '\\':
+				dirs[0] = s.dir.mirror(r)
+			case 'x':
+				dirs = []Direction{Up, Down}
+			case '!', '?':
+				n = 2
+			}
+		}
+		for _, d := range dirs {
+			if d == Up && s.y < n || d == Down && s.y+n >= cB.height {
+				w, found = Warning{s.x, s.y, "the ><> may wrap vertically through the row which would set up its " +
+					"stack, so nothing was evaluated"}, true
+			}
+		}
+	})
+	return
+}
@@ -0,0 +1,50 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestPartialEvaluate(t *testing.T) {
+	for _, test := range []struct {
+		script, want string
+		steps        int
+	}{
+		{"i1+n;", "i1+n;", 0},
+		{"aa*:*5&v\n;n&n+1 <", "5&2f*e+f*6+f*a+61.\naa*:*5&v\n;n&n+1 <", 7},
+		{"09-\"a\"v\n    ;o<", "09-6f*7+51.\n09-\"a\"v\n    ;o<", 6},
+		{"11[2]v\n ;nn<", "1111.\n11[2]v\n ;nn< ", 2},
+	} {
+		got, steps, warnings, err := PartialEvaluate(test.script, 1000)
+		if err != nil || len(warnings) != 0 {
+			t.Fatal(err, warnings)
+		}
+		if got != test.want || steps != test.steps {
+			t.Errorf("%q: got %q after %d steps, want %q after %d", test.script, got, steps, test.want,
+				test.steps)
+			continue
+		}
+		if want, got := runOutput(test.script), runOutput(got); got != want {
+			t.Errorf("%q: residual wrote %q, want %q", test.script, got, want)
+		}
+	}
+
+	_, _, warnings, err := PartialEvaluate("12+00g.", 1000)
+	if err != nil || len(warnings) != 1 || warnings[0].Y != 1 {
+		t.Error(err, warnings)
+	}
+
+	// Wrapping up from the first row would pass through the row setting up the stack
+	got, steps, warnings, err := PartialEvaluate("12^\n  ;\n  n", 1000)
+	if err != nil || got != "12^\n  ;\n  n" || steps != 0 || len(warnings) != 1 || warnings[0].X != 2 ||
+		warnings[0].Y != 0 {
+		t.Errorf("got %q after %d steps, %v, %v", got, steps, warnings, err)
+	}
+}
+
+func runOutput(script string) string {
+	var log EventLog
+	cB := NewCodeBox(script, nil, Spec, WithEventLog(&log))
+	for i := 0; i < 1000 && !cB.swim(); i++ {
+	}
+	return log.Output()
+}
@@ -0,0 +1,105 @@
+package fish
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+)
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// plottable returns true unless v is NaN, as expressions give for missing values, or infinite. Values which
+// can't be plotted are left as gaps.
+func plottable(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// bounds returns the smallest and largest plottable values in s.
+func (s *Series) bounds() (min, max float64) {
+	seen := false
+	for _, v := range s.Values {
+		if !plottable(v) {
+			continue
+		}
+		if !seen || v < min {
+			min = v
+		}
+		if !seen || v > max {
+			max = v
+		}
+		seen = true
+	}
+	return
+}
+
+// Deltas returns a Series of the change in s between each sample, such as the output rate from
+// OutputCount.
+func (s *Series) Deltas() *Series {
+	d := &Series{Name: s.Name}
+	for i := 1; i < len(s.Values); i++ {
+		d.Steps = append(d.Steps, s.Steps[i])
+		d.Values = append(d.Values, s.Values[i]-s.Values[i-1])
+	}
+	return d
+}
+
+// Sparkline renders s as a line of block characters, scaled between its smallest and largest values, with a
+// space for each value which can't be plotted.
+func (s *Series) Sparkline() string {
+	min, max := s.bounds()
+	var b strings.Builder
+	for _, v := range s.Values {
+		if !plottable(v) {
+			b.WriteRune(' ')
+			continue
+		}
+		i := 0
+		if max > min {
+			i = int((v - min) / (max - min) * float64(len(sparks)-1))
+		}
+		b.WriteRune(sparks[i])
+	}
+	return b.String()
+}
+
+// WriteSVG renders s to w as an SVG line chart of the given size, with its name, range and steps labelled.
+// The line is broken wherever a value can't be plotted.
+func (s *Series) WriteSVG(w io.Writer, width, height int) error {
+	const margin = 20
+	min, max := s.bounds()
+	first, last := 0, 0
+	if len(s.Steps) > 0 {
+		first, last = s.Steps[0], s.Steps[len(s.Steps)-1]
+	}
+	var lines, points []string
+	endLine := func() {
+		if len(points) > 0 {
+			lines = append(lines, fmt.Sprintf(`<polyline fill="none" stroke="black" points="%s"/>`,
+				strings.Join(points, " ")))
+		}
+		points = nil
+	}
+	for i, v := range s.Values {
+		if !plottable(v) {
+			endLine()
+			continue
+		}
+		x, y := float64(margin), float64(height-margin)
+		if last > first {
+			x += float64(s.Steps[i]-first) / float64(last-first) * float64(width-2*margin)
+		}
+		if max > min {
+			y -= (v - min) / (max - min) * float64(height-2*margin)
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	endLine()
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">
+<text x="%d" y="%d" font-size="12">%s (%v to %v, steps %d to %d)</text>
+%s
+</svg>
+`, width, height, margin, margin-6, html.EscapeString(s.Name), min, max, first, last, strings.Join(lines, "\n"))
+	return err
+}
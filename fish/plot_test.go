@@ -0,0 +1,60 @@
+package fish
+
+import (
+	"bytes"
+	"encoding/xml"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPlot(t *testing.T) {
+	s := &Series{Name: "depth", Steps: []int{0, 1, 2, 3}, Values: []float64{0, 7, 14, 0}}
+	if got := s.Sparkline(); got != "▁▄█▁" {
+		t.Errorf("got %q", got)
+	}
+	if got := (&Series{Values: []float64{3, 3}}).Sparkline(); got != "▁▁" {
+		t.Errorf("got %q", got)
+	}
+	if got := (&Series{Values: []float64{math.NaN(), 0, math.NaN(), 7, math.Inf(1)}}).Sparkline(); got != " ▁ █ " {
+		t.Errorf("got %q with gaps", got)
+	}
+
+	d := s.Deltas()
+	if len(d.Values) != 3 || d.Values[2] != -14 || d.Steps[0] != 1 {
+		t.Errorf("%+v", d)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := s.WriteSVG(buf, 140, 60); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `points="20.0,40.0 53.3,30.0 86.7,20.0 120.0,40.0"`) ||
+		!strings.Contains(buf.String(), "depth (0 to 14, steps 0 to 3)") {
+		t.Error(buf)
+	}
+
+	buf.Reset()
+	s = &Series{Name: "depth > 0 && stack[0] < 5", Steps: []int{0, 1, 2, 3},
+		Values: []float64{math.NaN(), 1, math.NaN(), 0}}
+	if err := s.WriteSVG(buf, 140, 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+		t.Errorf("%v: %s", err, buf)
+	}
+	if strings.Count(buf.String(), "<polyline") != 2 || !strings.Contains(buf.String(), "(0 to 1, steps 0 to 3)") {
+		t.Error(buf)
+	}
+}
+
+func TestOutputCount(t *testing.T) {
+	w := NewWatch(1)
+	out := w.Add("output", OutputCount)
+	cB := NewCodeBox("1n2n;", nil, Spec, WithWatch(w))
+	for !cB.swim() {
+	}
+	if got := out.Deltas().Sparkline(); got != "▁█▁█" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,239 @@
+package fish
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Origin is the file and position a cell of a generated codebox came from.
+type Origin struct {
+	File string
+	X, Y int
+}
+
+func (o Origin) String() string {
+	return fmt.Sprintf("%s:(%d,%d)", o.File, o.X, o.Y)
+}
+
+// SourceMap maps the cells of a generated codebox back to where they came from.
+type SourceMap struct {
+	cells map[[2]int]Origin
+}
+
+// Lookup returns the Origin of the cell at (x,y), and whether it has one. Padding added to make the codebox
+// rectangular has no Origin.
+func (m *SourceMap) Lookup(x, y int) (Origin, bool) {
+	o, ok := m.cells[[2]int{x, y}]
+	return o, ok
+}
+
+// WithSourceMap attaches m to the CodeBox, so errors can be reported in terms of the original source.
+func WithSourceMap(m *SourceMap) Option {
+	return func(cB *CodeBox) {
+		cB.sourceMap = m
+	}
+}
+
+// Origin returns the Origin of the cell the ><> is on, and whether it has one. It never has one unless
+// WithSourceMap was used.
+func (cB *CodeBox) Origin() (Origin, bool) {
+	if cB.sourceMap == nil {
+		return Origin{}, false
+	}
+	return cB.sourceMap.Lookup(cB.fX, cB.fY)
+}
+
+// Preprocessed is the result of Preprocess.
+type Preprocessed struct {
+	Script   string
+	Map      *SourceMap
+	Warnings []Warning
+}
+
+// Preprocess reads the ><> script at path and resolves its directives, returning a script ready for
+// NewCodeBox. The only directive is:
+//
+//	#include <file> <x> <y>
+//
+// which must be on a line of its own, and places the script in file (relative to the including file) at
+// (x,y), overwriting anything there. Directive lines don't become rows of the codebox. Included scripts are
+// moved with Relocate, and its warnings are returned with their positions in the result.
+func Preprocess(path string, opts ...PreprocessOption) (*Preprocessed, error) {
+	p := &preprocessor{read: ioutil.ReadFile}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p.run(path)
+}
+
+// PreprocessOption configures Preprocess.
+type PreprocessOption func(p *preprocessor)
+
+// StripAssertions replaces each assertion ("A", see AssertExtension) which may be executed with "~", which
+// discards the value it would have checked, so a script can be released without AssertExtension.
+func StripAssertions() PreprocessOption {
+	return func(p *preprocessor) {
+		p.strip = true
+	}
+}
+
+type cell struct {
+	r      byte
+	origin Origin
+}
+
+type preprocessor struct {
+	read     func(path string) ([]byte, error)
+	stack    []string
+	warnings []Warning
+	strip    bool
+}
+
+func (p *preprocessor) run(path string) (*Preprocessed, error) {
+	grid, size, err := p.file(path)
+	if err != nil {
+		return nil, err
+	}
+	// The codebox is at least as large as the file, so trailing spaces and empty rows are kept
+	width, height := size[0], size[1]
+	for pos := range grid {
+		if pos[0]+1 > width {
+			width = pos[0] + 1
+		}
+		if pos[1]+1 > height {
+			height = pos[1] + 1
+		}
+	}
+	m := &SourceMap{make(map[[2]int]Origin)}
+	lines := make([]string, height)
+	for y := range lines {
+		line := make([]byte, width)
+		for x := range line {
+			c, ok := grid[[2]int{x, y}]
+			if !ok {
+				line[x] = ' '
+				continue
+			}
+			line[x] = c.r
+			m.cells[[2]int{x, y}] = c.origin
+		}
+		lines[y] = string(line)
+	}
+	script := strings.Join(lines, "\n")
+	if p.strip && strings.IndexByte(script, 'A') >= 0 {
+		cB := NewCodeBox(script, nil, Spec, WithExtension(AssertExtension{}))
+		kinds := cB.CellKinds()
+		for y, line := range cB.box {
+			for x, r := range line {
+				if r == 'A' && kinds[y][x] == Code {
+					lines[y] = lines[y][:x] + "~" + lines[y][x+1:]
+				}
+			}
+		}
+		script = strings.Join(lines, "\n")
+	}
+	return &Preprocessed{script, m, p.warnings}, nil
+}
+
+// file returns the cells of the script at path with its directives resolved, and the width and height of
+// the script itself.
+func (p *preprocessor) file(path string) (map[[2]int]cell, [2]int, error) {
+	var size [2]int
+	for _, s := range p.stack {
+		if s == path {
+			return nil, size, fmt.Errorf("%s: include cycle: %s -> %s", path, strings.Join(p.stack, " -> "), path)
+		}
+	}
+	p.stack = append(p.stack, path)
+	defer func() {
+		p.stack = p.stack[:len(p.stack)-1]
+	}()
+
+	b, err := p.read(path)
+	if err != nil {
+		return nil, size, err
+	}
+	type directive struct {
+		n, x, y int
+		file    string
+	}
+	var includes []directive
+	grid := make(map[[2]int]cell)
+	y := 0
+	for n, line := range splitScript(string(b)) {
+		if !strings.HasPrefix(line, "#include ") {
+			for x := range line {
+				grid[[2]int{x, y}] = cell{line[x], Origin{path, x, y}}
+			}
+			if len(line) > size[0] {
+				size[0] = len(line)
+			}
+			y++
+			continue
+		}
+		d := directive{n: n + 1}
+		if _, err := fmt.Sscanf(line, "#include %s %d %d", &d.file, &d.x, &d.y); err != nil || d.x < 0 || d.y < 0 {
+			return nil, size, fmt.Errorf("%s:%d: malformed directive %q", path, d.n, line)
+		}
+		if !filepath.IsAbs(d.file) {
+			d.file = filepath.Join(filepath.Dir(path), d.file)
+		}
+		includes = append(includes, d)
+	}
+	size[1] = y
+	for _, d := range includes {
+		if err := p.include(grid, d.file, d.x, d.y); err != nil {
+			return nil, size, fmt.Errorf("%s:%d: %v", path, d.n, err)
+		}
+	}
+	return grid, size, nil
+}
+
+// include places the script in file into grid at (ix,iy), relocating it to its new position.
+func (p *preprocessor) include(grid map[[2]int]cell, file string, ix, iy int) error {
+	inc, _, err := p.file(file)
+	if err != nil || len(inc) == 0 {
+		return err
+	}
+	var width, height int
+	for pos := range inc {
+		if pos[0]+1 > width {
+			width = pos[0] + 1
+		}
+		if pos[1]+1 > height {
+			height = pos[1] + 1
+		}
+	}
+	lines := make([][]byte, height)
+	for y := range lines {
+		lines[y] = []byte(strings.Repeat(" ", width))
+		for x := range lines[y] {
+			if c, ok := inc[[2]int{x, y}]; ok {
+				lines[y][x] = c.r
+			}
+		}
+	}
+	rows := make([]string, len(lines))
+	for y := range lines {
+		rows[y] = string(lines[y])
+	}
+	// The included script's cells must stay where they are, so coordinates aren't given room to grow
+	r, err := relocate(strings.Join(rows, "\n"), ix, iy, true)
+	if err != nil {
+		return err
+	}
+	for _, w := range r.warnings {
+		w.X, w.Y = w.X+ix, w.Y+iy
+		p.warnings = append(p.warnings, w)
+	}
+	for y, line := range r.lines {
+		for x := range line {
+			if c, ok := inc[[2]int{x, y}]; ok {
+				grid[[2]int{x + ix, y + iy}] = cell{line[x], c.origin}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package fish
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func testPreprocessor(files map[string]string) *preprocessor {
+	return &preprocessor{read: func(path string) ([]byte, error) {
+		if s, ok := files[path]; ok {
+			return []byte(s), nil
+		}
+		return nil, os.ErrNotExist
+	}}
+}
+
+func TestPreprocessInclude(t *testing.T) {
+	p := testPreprocessor(map[string]string{
+		"main.fish":      "v\n#include lib/hello.fish 1 1\n>\n;",
+		"lib/hello.fish": "12.\n;",
+	})
+	pp, err := p.run("main.fish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "v   \n>23.\n;;  "; pp.Script != want {
+		t.Errorf("got %q, want %q", pp.Script, want)
+	}
+	if o, ok := pp.Map.Lookup(3, 1); !ok || o != (Origin{"lib/hello.fish", 2, 0}) {
+		t.Error(o)
+	}
+	if o, ok := pp.Map.Lookup(0, 2); !ok || o != (Origin{"main.fish", 0, 2}) {
+		t.Error(o)
+	}
+	if _, ok := pp.Map.Lookup(3, 2); ok {
+		t.Fail()
+	}
+}
+
+func TestPreprocessKeepsShape(t *testing.T) {
+	// Trailing spaces and empty rows are part of the codebox, and can be read with "g"
+	for script, want := range map[string]string{
+		"70gn;   ":    "70gn;   ",
+		"v\n\n":       "v\n \n ",
+		"03gn;\n\n\n": "03gn;\n     \n     \n     ",
+	} {
+		pp, err := testPreprocessor(map[string]string{"main.fish": script}).run("main.fish")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pp.Script != want {
+			t.Errorf("got %q, want %q", pp.Script, want)
+		}
+	}
+}
+
+func TestPreprocessErrors(t *testing.T) {
+	for _, files := range []map[string]string{
+		{"main.fish": "#include a.fish 0 0", "a.fish": "#include main.fish 0 0"},
+		{"main.fish": "#include a.fish"},
+		{"main.fish": "#include missing.fish 0 0"},
+	} {
+		if _, err := testPreprocessor(files).run("main.fish"); err == nil {
+			t.Error(fmt.Sprint(files))
+		}
+	}
+}
+
+func TestWithSourceMap(t *testing.T) {
+	p := testPreprocessor(map[string]string{
+		"main.fish": "#include a.fish 0 0\n  ;",
+		"a.fish":    " >",
+	})
+	pp, err := p.run("main.fish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cB := NewCodeBox(pp.Script, []float64{}, Spec, WithSourceMap(pp.Map))
+	cB.Swim()
+	if o, ok := cB.Origin(); !ok || o != (Origin{"a.fish", 1, 0}) {
+		t.Error(o)
+	}
+	if _, ok := NewCodeBox(pp.Script, []float64{}, Spec).Origin(); ok {
+		t.Fail()
+	}
+}
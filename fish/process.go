@@ -0,0 +1,165 @@
+package fish
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// The process protocol lets a separate program provide extension instructions, so extensions can be
+// distributed without rebuilding the interpreter. Messages are JSON objects, one per line. When it starts,
+// the program writes a processHello listing its instructions. Then, each time the ><> executes one of
+// them, the interpreter writes a processCall, and the program replies with a processReply. The program
+// should exit when its input is closed.
+
+// processHello is the first message sent by a process, declaring its Manifest.
+type processHello struct {
+	Ops   string       `json:"ops"`             // The instructions the process provides
+	Needs []Capability `json:"needs,omitempty"` // The capabilities it needs
+}
+
+// processCall asks a process to execute an instruction.
+type processCall struct {
+	Op    string    `json:"op"`
+	Stack []float64 `json:"stack"` // The current stack
+}
+
+// processReply is a process's reply to a processCall.
+type processReply struct {
+	Stack  []float64 `json:"stack"`            // The current stack after the instruction
+	Output string    `json:"output,omitempty"` // Output to write for the ><>
+	Error  string    `json:"error,omitempty"`  // Why the instruction failed, if it did
+}
+
+// Process is an Extension whose instructions are executed by another program, speaking the protocol
+// implemented by ServeProcess. It may be shared between CodeBoxes, whose calls are then made one at a time.
+type Process struct {
+	cmd   *exec.Cmd
+	in    io.WriteCloser
+	enc   *json.Encoder
+	dec   *json.Decoder
+	ops   string
+	needs []Capability
+	mu    sync.Mutex
+}
+
+// StartProcess starts the program name with args, and reads the instructions it provides. The program's
+// stderr is passed through to os.Stderr, as it was when StartProcess was called, so its diagnostics reach
+// the user. Close stops it.
+func StartProcess(name string, args ...string) (*Process, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &Process{cmd: cmd, in: in, enc: json.NewEncoder(in), dec: json.NewDecoder(bufio.NewReader(out))}
+	var hello processHello
+	if err = p.dec.Decode(&hello); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("%s: reading instructions: %v", name, err)
+	}
+	p.ops, p.needs = hello.Ops, hello.Needs
+	return p, nil
+}
+
+// Manifest implements Declarer, returning the manifest declared by the process, named after its program.
+func (p *Process) Manifest() Manifest {
+	return Manifest{Name: p.cmd.Path, Ops: p.ops, Needs: p.needs}
+}
+
+// Ops returns the instructions the process provides.
+func (p *Process) Ops() string {
+	return p.ops
+}
+
+// Instructions implements Extension.
+func (p *Process) Instructions() map[byte]Instruction {
+	m := make(map[byte]Instruction, len(p.ops))
+	for i := 0; i < len(p.ops); i++ {
+		op := p.ops[i]
+		m[op] = func(cB *CodeBox) {
+			p.call(cB, op)
+		}
+	}
+	return m
+}
+
+// call executes op in the process, replacing the current stack with the one it returns.
+func (p *Process) call(cB *CodeBox, op byte) {
+	p.mu.Lock()
+	var reply processReply
+	err := p.enc.Encode(processCall{string(op), cB.Stack()})
+	if err == nil {
+		err = p.dec.Decode(&reply)
+	}
+	p.mu.Unlock()
+	if err != nil {
+		panic(fmt.Errorf("Extension %q failed: %v", op, err))
+	}
+	if reply.Error != "" {
+		panic(errors.New(reply.Error))
+	}
+	for n := len(cB.Stack()); n > 0; n-- {
+		cB.Pop()
+	}
+	for _, v := range reply.Stack {
+		cB.Push(v)
+	}
+	fmt.Fprint(cB.stdout(), reply.Output)
+}
+
+// Close closes the process's input, and waits for it to exit.
+func (p *Process) Close() error {
+	p.in.Close()
+	return p.cmd.Wait()
+}
+
+// ProcessInstruction executes op for a program serving a Process, given the current stack. It returns the
+// stack as it should be afterwards, and any output for the ><>. If it returns an error, the ><> fails.
+type ProcessInstruction func(op byte, stack []float64) (after []float64, output string, err error)
+
+// ServeProcess implements the program side of a Process, providing the instructions in ops by calling f,
+// which needs the capabilities in needs. It reads calls from r and writes replies to w, usually os.Stdin and
+// os.Stdout, until r ends.
+func ServeProcess(ops string, needs []Capability, f ProcessInstruction, r io.Reader, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(processHello{ops, needs}); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var call processCall
+		if err := dec.Decode(&call); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(call.Op) != 1 {
+			return fmt.Errorf("invalid instruction %q", call.Op)
+		}
+		var reply processReply
+		var err error
+		if reply.Stack, reply.Output, err = f(call.Op[0], call.Stack); err != nil {
+			reply = processReply{Stack: call.Stack, Error: err.Error()}
+		}
+		if reply.Stack == nil {
+			reply.Stack = []float64{}
+		}
+		if err := enc.Encode(reply); err != nil {
+			return err
+		}
+	}
+}
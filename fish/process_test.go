@@ -0,0 +1,86 @@
+package fish
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestProcessHelper isn't a real test: it's the program run by TestProcess, serving "S", which sums the
+// stack, and "W", which outputs the stack's length in words. It complains on stderr when asked to sum
+// nothing.
+func TestProcessHelper(t *testing.T) {
+	if os.Getenv("FISH_PROCESS_HELPER") != "1" {
+		return
+	}
+	err := ServeProcess("WS", []Capability{Clock}, func(op byte, stack []float64) ([]float64, string, error) {
+		if len(stack) == 0 {
+			fmt.Fprintln(os.Stderr, "helper: the stack is empty")
+			return nil, "", errors.New("Nothing to sum!")
+		}
+		if op == 'W' {
+			return stack, []string{"none", "one", "two"}[len(stack)%3], nil
+		}
+		sum := 0.0
+		for _, v := range stack {
+			sum += v
+		}
+		return []float64{sum}, "", nil
+	}, os.Stdin, os.Stdout)
+	if err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestProcess(t *testing.T) {
+	stderr, err := ioutil.TempFile("", "gofish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+	os.Setenv("FISH_PROCESS_HELPER", "1")
+	os.Stderr, stderr = stderr, os.Stderr
+	p, err := StartProcess(os.Args[0], "-test.run=^TestProcessHelper$")
+	os.Stderr, stderr = stderr, os.Stderr
+	os.Unsetenv("FISH_PROCESS_HELPER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := p.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+	if p.Ops() != "WS" {
+		t.Fatalf("got instructions %q", p.Ops())
+	}
+	if _, err := Approve(p); !errors.Is(err, ErrDenied) {
+		t.Errorf("got %v, want ErrDenied for the undeclared clock", err)
+	}
+	if m, err := Approve(p, Clock); err != nil || m.Ops != "WS" {
+		t.Errorf("got %+v, %v", m, err)
+	}
+
+	out := new(bytes.Buffer)
+	cB := NewCodeBox("12W3Sn;", nil, Spec, WithExtension(p), WithOutput(out))
+	if _, err := cB.Run(100); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "two6" {
+		t.Errorf("got output %q", out)
+	}
+
+	cB = NewCodeBox("S;", nil, Spec, WithExtension(p))
+	if _, err := cB.Run(100); err == nil || !strings.Contains(err.Error(), "Nothing to sum!") {
+		t.Errorf("got %v, want the process's error", err)
+	}
+	if b, err := ioutil.ReadFile(stderr.Name()); err != nil || string(b) != "helper: the stack is empty\n" {
+		t.Errorf("got stderr %q, %v", b, err)
+	}
+}
@@ -0,0 +1,90 @@
+package fish
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+)
+
+// Region is a named rectangle of the codebox, such as "main loop" or "print routine".
+type Region struct {
+	Name                string
+	X, Y, Width, Height int
+}
+
+// Contains returns true if (x,y) is inside r.
+func (r Region) Contains(x, y int) bool {
+	return x >= r.X && y >= r.Y && x < r.X+r.Width && y < r.Y+r.Height
+}
+
+// Profile counts the steps a ><> spends in each of its Regions. If the CodeBox has a source map, steps are
+// also counted for each file cells came from. Attach a Profile to a CodeBox with WithProfile.
+type Profile struct {
+	Regions []Region
+	Steps   map[string]int // Steps spent in each Region, keyed by name
+	Files   map[string]int // Steps spent in cells from each file
+	Other   int            // Steps spent outside every Region
+	Total   int
+}
+
+// NewProfile returns a pointer to a Profile of regions.
+func NewProfile(regions ...Region) *Profile {
+	return &Profile{Regions: regions, Steps: make(map[string]int), Files: make(map[string]int)}
+}
+
+// WithProfile counts the steps the ><> executes in p.
+func WithProfile(p *Profile) Option {
+	return func(cB *CodeBox) {
+		cB.observers = append(cB.observers, func(x, y int) {
+			p.Total++
+			in := false
+			for _, r := range p.Regions {
+				if r.Contains(x, y) {
+					p.Steps[r.Name]++
+					in = true
+				}
+			}
+			if !in {
+				p.Other++
+			}
+			if cB.sourceMap != nil {
+				if o, ok := cB.sourceMap.Lookup(x, y); ok {
+					p.Files[o.File]++
+				}
+			}
+		})
+	}
+}
+
+// String returns a table of the steps spent in each Region and file, busiest first.
+func (p *Profile) String() string {
+	type row struct {
+		name  string
+		steps int
+	}
+	var rows []row
+	for _, r := range p.Regions {
+		rows = append(rows, row{r.Name, p.Steps[r.Name]})
+	}
+	for f, n := range p.Files {
+		rows = append(rows, row{"file " + f, n})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].steps > rows[j].steps
+	})
+	rows = append(rows, row{"(other)", p.Other})
+
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "REGION\tSTEPS\t%")
+	for _, r := range rows {
+		pct := 0.0
+		if p.Total > 0 {
+			pct = float64(r.steps) * 100 / float64(p.Total)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%.1f\n", r.name, r.steps, pct)
+	}
+	w.Flush()
+	return buf.String()
+}
@@ -0,0 +1,20 @@
+package fish
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	p := NewProfile(Region{"setup", 0, 0, 2, 1}, Region{"loop", 0, 1, 9, 1}, Region{"test", 5, 1, 3, 1})
+	cB := NewCodeBox("5v\n >1-:0=?;", []float64{}, Spec, WithProfile(p))
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if p.Total != 42 || p.Steps["setup"] != 2 || p.Steps["loop"] != 40 || p.Steps["test"] != 15 || p.Other != 0 {
+		t.Fatal(p.Total, p.Steps, p.Other)
+	}
+	if !strings.HasPrefix(p.String(), "REGION   STEPS  %\nloop     40     95.2\ntest     15     35.7\n") {
+		t.Error(p.String())
+	}
+}
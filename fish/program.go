@@ -0,0 +1,48 @@
+package fish
+
+// Program is a ><> script which has been parsed and validated once, so that many CodeBoxes can be started
+// from it cheaply, e.g. when judging or fuzzing a script with many different inputs. A Program is never
+// modified after Compile returns, so it may be used from multiple goroutines.
+type Program struct {
+	script        string
+	box           [][]byte
+	width, height int
+	mode          Mode
+	warnings      []Warning
+}
+
+// Compile parses and validates script. mode is as for NewCodeBox. It returns ErrEmptyScript if script is
+// empty or only blank lines.
+func Compile(script string, mode Mode) (*Program, error) {
+	cB := NewCodeBox(script, nil, mode)
+	if cB.err != nil {
+		return nil, cB.err
+	}
+	return &Program{script, cB.box, cB.width, cB.height, mode, cB.Validate()}, nil
+}
+
+// Warnings returns the likely mistakes found in the Program, as returned by CodeBox.Validate.
+func (p *Program) Warnings() []Warning {
+	return p.warnings
+}
+
+// Size returns the width and height of the Program's codebox.
+func (p *Program) Size() (width, height int) {
+	return p.width, p.height
+}
+
+// New returns a fresh CodeBox running the Program, with stack as its initial stack. opts are applied as
+// they are by NewCodeBox. The codebox is shared with the Program and copied on write, as it is by Clone,
+// so New is cheap however large the Program is.
+func (p *Program) New(stack []float64, opts ...Option) *CodeBox {
+	cB := &CodeBox{width: p.width, height: p.height, box: append([][]byte(nil), p.box...)}
+	cB.shared = make([]bool, len(p.box))
+	for i := range cB.shared {
+		cB.shared[i] = true
+	}
+	cB.start(stack, p.mode, opts)
+	if cB.runes {
+		cB.layoutRunes(p.script)
+	}
+	return cB
+}
@@ -0,0 +1,43 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	for _, script := range []string{"", "\r\n", "\n\n", "\n\n\n"} {
+		if _, err := Compile(script, Spec); err != ErrEmptyScript {
+			t.Errorf("compiling %q got %v, want ErrEmptyScript", script, err)
+		}
+	}
+	p, err := Compile("'a\n;", Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := p.Size(); w != 2 || h != 2 {
+		t.Errorf("got size %dx%d, want 2x2", w, h)
+	}
+	if len(p.Warnings()) != 1 {
+		t.Errorf("got warnings %v, want one for the unclosed string", p.Warnings())
+	}
+}
+
+func TestProgramNew(t *testing.T) {
+	p, err := Compile("00g\"a\"00p;", Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stack := []float64{1}
+	for i := 0; i < 3; i++ {
+		cB := p.New(stack)
+		if _, err := cB.Run(0); err != nil {
+			t.Fatal(err)
+		}
+		if s := cB.Stack(); len(s) != 2 || s[0] != 1 || s[1] != '0' {
+			t.Errorf("run %d: got stack %v, want [1 %d]", i, s, '0')
+		}
+	}
+	if len(stack) != 1 || p.box[0][0] != '0' {
+		t.Errorf("running modified the initial stack %v or the program %q", stack, p.box[0])
+	}
+}
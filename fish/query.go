@@ -0,0 +1,114 @@
+package fish
+
+import (
+	"fmt"
+)
+
+// Each calls f with every step in the trace, in order, until f returns false.
+func (tr *TraceReader) Each(f func(s TraceStep) bool) error {
+	for i := 0; i < tr.steps; i++ {
+		s, err := tr.At(i)
+		if err != nil {
+			return err
+		}
+		if !f(s) {
+			break
+		}
+	}
+	return nil
+}
+
+// Find returns the steps in the trace for which match returns true.
+func (tr *TraceReader) Find(match func(s TraceStep) bool) (steps []int, err error) {
+	err = tr.Each(func(s TraceStep) bool {
+		if match(s) {
+			steps = append(steps, s.Step)
+		}
+		return true
+	})
+	return
+}
+
+// Executed returns the steps at which the ><> was on the cell (x,y).
+func (tr *TraceReader) Executed(x, y int) ([]int, error) {
+	return tr.Find(func(s TraceStep) bool {
+		return s.X == x && s.Y == y
+	})
+}
+
+// FirstDeeper returns the first step before which the current stack held more than n values, and false if
+// there is none.
+func (tr *TraceReader) FirstDeeper(n int) (step int, ok bool, err error) {
+	err = tr.Each(func(s TraceStep) bool {
+		step, ok = s.Step, len(s.Stack) > n
+		return !ok
+	})
+	return
+}
+
+// Write is a "p" found in a trace.
+type Write struct {
+	Step  int
+	X, Y  int
+	Value float64
+}
+
+// Writes returns every "p" in the trace which had enough values on the stack to execute, along with the
+// cell and value it wrote.
+func (tr *TraceReader) Writes() (writes []Write, err error) {
+	err = tr.Each(func(s TraceStep) bool {
+		if n := len(s.Stack); s.Op == 'p' && !s.String && n >= 3 {
+			writes = append(writes, Write{s.Step, int(s.Stack[n-2]), int(s.Stack[n-1]), s.Stack[n-3]})
+		}
+		return true
+	})
+	return
+}
+
+// Compare returns the first step at which the trace differs from other, such as a trace of the same script
+// recorded by an earlier version of the interpreter, or nil if they're the same.
+func (tr *TraceReader) Compare(other *TraceReader) (*Divergence, error) {
+	for i := 0; i < tr.steps && i < other.steps; i++ {
+		s, err := tr.At(i)
+		if err != nil {
+			return nil, err
+		}
+		s2, err := other.At(i)
+		if err != nil {
+			return nil, err
+		}
+		var reason string
+		switch {
+		case s.X != s2.X || s.Y != s2.Y:
+			reason = fmt.Sprintf("the ><> is at (%d,%d), not (%d,%d)", s2.X, s2.Y, s.X, s.Y)
+		case s.Dir != s2.Dir:
+			reason = fmt.Sprintf("the ><> is moving %v, not %v", s2.Dir, s.Dir)
+		case s.Op != s2.Op || s.String != s2.String:
+			reason = fmt.Sprintf("the instruction is %q, not %q", s2.Op, s.Op)
+		case s.Stacks != s2.Stacks:
+			reason = fmt.Sprintf("there are %d stacks, not %d", s2.Stacks, s.Stacks)
+		case !sameValues(s.Stack, s2.Stack):
+			reason = fmt.Sprintf("the stack is %v, not %v", s2.Stack, s.Stack)
+		}
+		if reason != "" {
+			return &Divergence{i, s.X, s.Y, s.Op, reason}, nil
+		}
+	}
+	if tr.steps == other.steps {
+		return nil, nil
+	}
+	// The traces match until the shorter one ends, so its last step is where they diverged.
+	n := tr.steps
+	if other.steps < n {
+		n = other.steps
+	}
+	d := &Divergence{Step: n, Reason: fmt.Sprintf("the ><> stopped after %d steps, not %d", other.steps, tr.steps)}
+	if n > 0 {
+		s, err := tr.At(n - 1)
+		if err != nil {
+			return nil, err
+		}
+		d.X, d.Y, d.Op = s.X, s.Y, s.Op
+	}
+	return d, nil
+}
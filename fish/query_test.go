@@ -0,0 +1,64 @@
+package fish
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTraceQueries(t *testing.T) {
+	tr := writeTrace(t, "\"p\"~a20p111v\n   ;    ~~~<", 3, true)
+
+	steps, err := tr.Executed(1, 0)
+	if err != nil || len(steps) != 1 || steps[0] != 1 {
+		t.Errorf("Executed: got %v, %v", steps, err)
+	}
+	if step, ok, err := tr.FirstDeeper(2); err != nil || !ok || step != 7 {
+		t.Errorf("FirstDeeper: got %d, %v, %v", step, ok, err)
+	}
+	if _, ok, _ := tr.FirstDeeper(10); ok {
+		t.Error("FirstDeeper should find nothing")
+	}
+	writes, err := tr.Writes()
+	if err != nil || len(writes) != 1 || writes[0] != (Write{7, 2, 0, 10}) {
+		t.Errorf("Writes: got %v, %v", writes, err)
+	}
+
+	n := 0
+	tr.Each(func(s TraceStep) bool {
+		n++
+		return n < 4
+	})
+	if n != 4 {
+		t.Errorf("Each continued after returning false: %d", n)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tr := writeTrace(t, "12+~;", 2, true)
+	if d, err := tr.Compare(writeTrace(t, "12+~;", 3, false)); d != nil || err != nil {
+		t.Errorf("got %v, %v comparing identical runs", d, err)
+	}
+	d, err := tr.Compare(writeTrace(t, "13+~;", 2, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d == nil || d.Step != 1 || d.Op != '2' || d.Reason != `the instruction is '3', not '2'` {
+		t.Errorf("got %v", d)
+	}
+
+	// A run which stops early.
+	buf := new(bytes.Buffer)
+	tw := NewTraceWriter(buf, 2, true)
+	cB := NewCodeBox("12+~;", nil, Spec, WithTrace(tw))
+	for i := 0; i < 2; i++ {
+		cB.swim()
+	}
+	tw.Close()
+	short, err := OpenTrace(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, _ = tr.Compare(short); d == nil || d.Step != 2 || d.X != 1 || d.Reason != "the ><> stopped after 2 steps, not 5" {
+		t.Errorf("got %v", d)
+	}
+}
@@ -0,0 +1,31 @@
+package fish
+
+import (
+	"strings"
+)
+
+// Instruction classes for use with WithQuota.
+const (
+	SelfModifying = "p"
+	Outputs       = "on"
+	Inputs        = "i"
+	Jumps         = ".!?"
+)
+
+// WithQuota allows the ><> to execute the instructions in ops at most max times in total, so hosts can
+// enforce puzzle constraints such as WithQuota(SelfModifying, 0). Exceeding the quota panics before the
+// offending instruction is executed.
+func WithQuota(ops string, max int) Option {
+	return func(cB *CodeBox) {
+		n := 0
+		cB.observers = append(cB.observers, func(x, y int) {
+			r := cB.box[y][x]
+			if cB.stringMode != 0 && r != cB.stringMode || strings.IndexByte(ops, r) < 0 {
+				return
+			}
+			if n++; n > max {
+				panic(failf(ErrQuota, "Quota exceeded: %q may only be executed %d time(s)!", ops, max))
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package fish
+
+import (
+	"testing"
+)
+
+func runQuota(cB *CodeBox) string {
+	for {
+		if done, fail := swimRecover(cB); done || fail != "" {
+			return fail
+		}
+	}
+}
+
+func TestQuota(t *testing.T) {
+	cB := NewCodeBox("1n2n\"no\"3n;", nil, Spec, WithQuota(Outputs, 2))
+	if got := runQuota(cB); got != `Quota exceeded: "on" may only be executed 2 time(s)!` {
+		t.Errorf("got %q", got)
+	}
+	if cB.fX != 9 {
+		t.Errorf("failed at %d, want 9", cB.fX)
+	}
+
+	cB = NewCodeBox("1n2n;", nil, Spec, WithQuota(Outputs, 2), WithQuota(SelfModifying, 0))
+	if got := runQuota(cB); got != "" {
+		t.Error(got)
+	}
+}
@@ -0,0 +1,68 @@
+package fish
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// regionColors are the ANSI background colours used to draw regions.
+var regionColors = []int{44, 42, 45, 43, 46, 41}
+
+// WithRegions labels the rectangles in regions, which PrintBox then draws in colour with a legend.
+func WithRegions(regions ...Region) Option {
+	return func(cB *CodeBox) {
+		cB.regions = append(cB.regions, regions...)
+	}
+}
+
+// ReadRegions reads regions from a sidecar file, which holds one region per line in the form
+//
+//	<x> <y> <width> <height> <name>
+//
+// The fields may be separated by any spaces or tabs, and the name is the rest of the line. Blank lines and
+// lines starting with "#" are ignored.
+func ReadRegions(r io.Reader) (regions []Region, err error) {
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		var reg Region
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("line %d: malformed region %q", n, line)
+		}
+		rest := line
+		for i, v := range []*int{&reg.X, &reg.Y, &reg.Width, &reg.Height} {
+			if *v, err = strconv.Atoi(fields[i]); err != nil {
+				return nil, fmt.Errorf("line %d: malformed region %q", n, line)
+			}
+			rest = strings.TrimSpace(rest)[len(fields[i]):]
+		}
+		reg.Name = strings.TrimSpace(rest)
+		regions = append(regions, reg)
+	}
+	return regions, s.Err()
+}
+
+// highlight returns cell coloured for the first region containing (x,y), if any.
+func (cB *CodeBox) highlight(x, y int, cell string) string {
+	for i, r := range cB.regions {
+		if r.Contains(x, y) {
+			return fmt.Sprintf("\x1b[%dm%s\x1b[0m", regionColors[i%len(regionColors)], cell)
+		}
+	}
+	return cell
+}
+
+// printLegend outputs the name and colour of each region.
+func (cB *CodeBox) printLegend() {
+	for i, r := range cB.regions {
+		fmt.Fprintf(cB.diag(), "\x1b[%dm   \x1b[0m %s (%d,%d %dx%d)\n", regionColors[i%len(regionColors)], r.Name,
+			r.X, r.Y, r.Width, r.Height)
+	}
+}
@@ -0,0 +1,38 @@
+package fish
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadRegions(t *testing.T) {
+	regions, err := ReadRegions(strings.NewReader("# regions\n0 0 4 1 main loop\n\n2 1 3 2 print"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Region{{"main loop", 0, 0, 4, 1}, {"print", 2, 1, 3, 2}}
+	if len(regions) != len(want) || regions[0] != want[0] || regions[1] != want[1] {
+		t.Fatal(regions)
+	}
+	if _, err = ReadRegions(strings.NewReader("0 0 x 1 broken")); err == nil {
+		t.Fail()
+	}
+	if _, err = ReadRegions(strings.NewReader("0 0 4 1")); err == nil {
+		t.Fail()
+	}
+
+	regions, err = ReadRegions(strings.NewReader("1\t2  3\t 4 \tmain  loop\t"))
+	if want := (Region{"main  loop", 1, 2, 3, 4}); err != nil || len(regions) != 1 || regions[0] != want {
+		t.Errorf("got %v, %v, want %v", regions, err, want)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	buf := new(bytes.Buffer)
+	cB := NewCodeBox("12;", []float64{}, Spec, WithRegions(Region{"a", 1, 0, 1, 1}), WithDiagnostics(buf))
+	cB.PrintBox()
+	if want := "\n*1*\x1b[44m 2 \x1b[0m ; \n\x1b[44m   \x1b[0m a (1,0 1x1)\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf, want)
+	}
+}
@@ -0,0 +1,311 @@
+package fish
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Reference is an instruction which uses absolute coordinates: ".", "g" or "p". If the coordinates are
+// pushed by the two literal instructions the ><> executes just before it, Static is set and CX and CY hold
+// the positions of the instructions pushing x and y.
+type Reference struct {
+	X, Y   int
+	Op     byte
+	Static bool
+	CX, CY [2]int
+}
+
+// References returns every Reference the ><> may execute, in reading order. Coordinates are only
+// considered static if they are pushed immediately before the instruction using them, in the direction the
+// ><> is swimming. Unreachable instructions are reported as though the ><> swims Right into them, as
+// routines in a library are often only reached by jumps.
+func (cB *CodeBox) References() (refs []Reference) {
+	seen := make(map[Reference]bool)
+	add := func(x, y int, d Direction) {
+		r := cB.box[y][x]
+		back := (d + 2) % 4
+		ref := Reference{X: x, Y: y, Op: r}
+		ref.CY[0], ref.CY[1] = cB.next(x, y, back, 1)
+		ref.CX[0], ref.CX[1] = cB.next(x, y, back, 2)
+		_, okX := literal(cB.box[ref.CX[1]][ref.CX[0]])
+		_, okY := literal(cB.box[ref.CY[1]][ref.CY[0]])
+		if ref.Static = okX && okY; !ref.Static {
+			ref.CX, ref.CY = [2]int{}, [2]int{}
+		}
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	kinds := cB.CellKinds()
+	cB.explore(func(s state, r byte) {
+		if s.stringMode == 0 && (r == '.' || r == 'g' || r == 'p') {
+			add(s.x, s.y, s.dir)
+		}
+	})
+	for y, line := range cB.box {
+		for x, r := range line {
+			if kinds[y][x] == 0 && (r == '.' || r == 'g' || r == 'p') {
+				add(x, y, Right)
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Y != refs[j].Y {
+			return refs[i].Y < refs[j].Y
+		}
+		return refs[i].X < refs[j].X
+	})
+	return
+}
+
+// Relocate rewrites the static coordinates used by script's References as though the script were moved
+// dx cells right and dy cells down, and returns the result, with every row as wide as the codebox. A
+// Warning is returned for each Reference whose coordinates are computed dynamically, as these can't be
+// relocated.
+//
+// A coordinate outside 0 to 15 takes more than one instruction to push, so Relocate makes room for it by
+// inserting blank columns beside the Reference, or blank rows if the ><> swims through it vertically, and
+// moves every static coordinate to match. It returns an error if the blank cells would change what the
+// script does: if the ><> may cross them in string mode or skip over one, or may swim through the
+// Reference's coordinates any other way.
+func Relocate(script string, dx, dy int) (string, []Warning, error) {
+	r, err := relocate(script, dx, dy, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.Join(r.lines, "\n"), r.warnings, nil
+}
+
+// relocation is a script rewritten by relocate.
+type relocation struct {
+	lines      []string // Each as wide as the codebox
+	cols, rows map[int]int
+	warnings   []Warning
+}
+
+// shift returns the position of column or row i of the original script, after inserting ins[k] columns or
+// rows before each column or row k.
+func shift(ins map[int]int, i int) int {
+	n := i
+	for k, c := range ins {
+		if k <= i {
+			n += c
+		}
+	}
+	return n
+}
+
+// pos returns the position of the cell at (x,y) in the original script.
+func (r *relocation) pos(x, y int) (int, int) {
+	return shift(r.cols, x), shift(r.rows, y)
+}
+
+// direction returns the direction the ><> swims through ref, and false if its instructions aren't in a
+// line, because they wrap around the codebox.
+func (ref Reference) direction() (Direction, bool) {
+	switch {
+	case ref.CX[1] == ref.Y && ref.CY[1] == ref.Y && ref.CX[0] == ref.X-2 && ref.CY[0] == ref.X-1:
+		return Right, true
+	case ref.CX[1] == ref.Y && ref.CY[1] == ref.Y && ref.CX[0] == ref.X+2 && ref.CY[0] == ref.X+1:
+		return Left, true
+	case ref.CX[0] == ref.X && ref.CY[0] == ref.X && ref.CX[1] == ref.Y-2 && ref.CY[1] == ref.Y-1:
+		return Down, true
+	case ref.CX[0] == ref.X && ref.CY[0] == ref.X && ref.CX[1] == ref.Y+2 && ref.CY[1] == ref.Y+1:
+		return Up, true
+	}
+	return 0, false
+}
+
+// span returns the cells of the relocated script from the first inserted ahead of ref's coordinates up to
+// its instruction, in the order the ><> swims through them.
+func (r *relocation) span(ref Reference, d Direction) (cells [][2]int) {
+	x, y := r.pos(ref.X, ref.Y)
+	cx, cy := r.pos(ref.CX[0], ref.CX[1])
+	switch d {
+	case Right:
+		for i := cx - r.cols[ref.CX[0]]; i < x; i++ {
+			cells = append(cells, [2]int{i, y})
+		}
+	case Left:
+		for i := cx + r.cols[ref.CX[0]+1]; i > x; i-- {
+			cells = append(cells, [2]int{i, y})
+		}
+	case Down:
+		for i := cy - r.rows[ref.CX[1]]; i < y; i++ {
+			cells = append(cells, [2]int{x, i})
+		}
+	case Up:
+		for i := cy + r.rows[ref.CX[1]+1]; i > y; i-- {
+			cells = append(cells, [2]int{x, i})
+		}
+	}
+	return
+}
+
+// reachable returns every state the ><> may reach from its current state.
+func (cB *CodeBox) reachable() (states []state) {
+	cB.explore(func(s state, r byte) {
+		states = append(states, s)
+	})
+	return
+}
+
+// crossing returns one of states in which the ><> crosses the boundary before column k, or before row k if
+// vertical is set, in a way which cells inserted there would change: in string mode, or by skipping over
+// it with "!" or "?". The boundary before column 0 is the edge the ><> wraps around.
+func (cB *CodeBox) crossing(states []state, k int, vertical bool) (state, bool) {
+	fwd, back, size := Right, Left, cB.width
+	if vertical {
+		fwd, back, size = Down, Up, cB.height
+	}
+	a, b := (k-1+size)%size, k%size
+	for _, s := range states {
+		i := s.x
+		if vertical {
+			i = s.y
+		}
+		skip := s.stringMode == 0 && (cB.box[s.y][s.x] == '!' || cB.box[s.y][s.x] == '?')
+		if s.dir == fwd && (i == b && s.stringMode != 0 || i == a && skip) ||
+			s.dir == back && (i == a && s.stringMode != 0 || i == b && skip) {
+			return s, true
+		}
+	}
+	return state{}, false
+}
+
+// relocate implements Relocate. If fixed is set, it returns an error rather than inserting cells.
+func relocate(script string, dx, dy int, fixed bool) (*relocation, error) {
+	cB := NewCodeBox(script, nil, Spec)
+	r := &relocation{cols: make(map[int]int), rows: make(map[int]int)}
+	var static []Reference
+	for _, ref := range cB.References() {
+		if ref.Static {
+			static = append(static, ref)
+			continue
+		}
+		r.warnings = append(r.warnings, Warning{ref.X, ref.Y,
+			fmt.Sprintf("coordinates used by %q are computed dynamically and can't be relocated", ref.Op)})
+	}
+	code := func(ref Reference) (string, error) {
+		x, _ := literal(cB.box[ref.CX[1]][ref.CX[0]])
+		y, _ := literal(cB.box[ref.CY[1]][ref.CY[0]])
+		nx, ny := dx+shift(r.cols, int(x)), dy+shift(r.rows, int(y))
+		if _, ok := literalFor(nx); !ok && fixed {
+			return "", fmt.Errorf("cannot relocate coordinate %v pushed at (%d,%d) to %d, outside 0 to 15", x,
+				ref.CX[0], ref.CX[1], nx)
+		} else if _, ok = literalFor(ny); !ok && fixed {
+			return "", fmt.Errorf("cannot relocate coordinate %v pushed at (%d,%d) to %d, outside 0 to 15", y,
+				ref.CY[0], ref.CY[1], ny)
+		}
+		return pushValue(nx) + pushValue(ny), nil
+	}
+
+	// Make room for each Reference's coordinates, until the room made moves none of them any further
+	for grown := true; grown; {
+		grown = false
+		for _, ref := range static {
+			c, err := code(ref)
+			if err != nil {
+				return nil, err
+			}
+			extra := len(c) - 2
+			if extra <= 0 {
+				continue
+			}
+			d, inLine := ref.direction()
+			if !inLine {
+				return nil, fmt.Errorf("cannot relocate the coordinates used by %q at (%d,%d), as they wrap around "+
+					"the codebox", ref.Op, ref.X, ref.Y)
+			}
+			ins, k := r.cols, ref.CX[0]
+			switch d {
+			case Left:
+				k++
+			case Down:
+				ins, k = r.rows, ref.CX[1]
+			case Up:
+				ins, k = r.rows, ref.CX[1]+1
+			}
+			if extra > ins[k] {
+				ins[k] = extra
+				grown = true
+			}
+		}
+	}
+
+	// Check that the inserted cells are only ever swum through as blanks, or as the code they'll hold
+	states := cB.reachable()
+	for _, axis := range []struct {
+		ins      map[int]int
+		vertical bool
+		name     string
+	}{{r.cols, false, "column"}, {r.rows, true, "row"}} {
+		for _, k := range sortedKeys(axis.ins) {
+			if s, ok := cB.crossing(states, k, axis.vertical); ok {
+				return nil, fmt.Errorf("cannot make room before %s %d to relocate coordinates, as the ><> at (%d,%d) "+
+					"may cross it in string mode or skip over it", axis.name, k, s.x, s.y)
+			}
+		}
+	}
+	for _, ref := range static {
+		if c, _ := code(ref); len(c) <= 2 {
+			continue
+		}
+		d, _ := ref.direction()
+		for _, s := range states {
+			if pos := [2]int{s.x, s.y}; (pos == ref.CX || pos == ref.CY) && (s.dir != d || s.stringMode != 0) {
+				return nil, fmt.Errorf("cannot relocate the coordinates used by %q at (%d,%d), as the ><> also "+
+					"swims through them %v", ref.Op, ref.X, ref.Y, s.dir)
+			}
+		}
+	}
+
+	width, height := r.pos(cB.width, cB.height)
+	box := make([][]byte, height)
+	for y := range box {
+		box[y] = []byte(strings.Repeat(" ", width))
+	}
+	for y, line := range cB.box {
+		for x, c := range line {
+			nx, ny := r.pos(x, y)
+			box[ny][nx] = c
+		}
+	}
+	for _, ref := range static {
+		c, _ := code(ref)
+		d, inLine := ref.direction()
+		if !inLine {
+			// Both coordinates still take a single instruction each
+			x, y := r.pos(ref.CX[0], ref.CX[1])
+			box[y][x] = c[0]
+			x, y = r.pos(ref.CY[0], ref.CY[1])
+			box[y][x] = c[1]
+			continue
+		}
+		cells := r.span(ref, d)
+		c = strings.Repeat(" ", len(cells)-len(c)) + c
+		for i, cell := range cells {
+			box[cell[1]][cell[0]] = c[i]
+		}
+	}
+	r.lines = make([]string, height)
+	for y := range box {
+		r.lines[y] = string(box[y])
+	}
+	for i, w := range r.warnings {
+		r.warnings[i].X, r.warnings[i].Y = r.pos(w.X, w.Y)
+	}
+	return r, nil
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[int]int) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,58 @@
+package fish
+
+import (
+	"strings"
+	"testing"
+)
+
+const relocateScript = "\\12.\n/~g43<\n:p"
+
+func TestReferences(t *testing.T) {
+	cB := NewCodeBox(relocateScript, []float64{}, Spec)
+	refs := cB.References()
+	want := []Reference{
+		{X: 3, Y: 0, Op: '.', Static: true, CX: [2]int{1, 0}, CY: [2]int{2, 0}},
+		{X: 2, Y: 1, Op: 'g', Static: true, CX: [2]int{4, 1}, CY: [2]int{3, 1}},
+		{X: 1, Y: 2, Op: 'p'},
+	}
+	if len(refs) != len(want) {
+		t.Fatal(refs)
+	}
+	for i := range want {
+		if refs[i] != want[i] {
+			t.Errorf("reference %d = %v, want %v", i, refs[i], want[i])
+		}
+	}
+}
+
+func TestRelocate(t *testing.T) {
+	script, warnings, err := Relocate(relocateScript, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\\33.  \n/~g55<\n:p    "; script != want {
+		t.Errorf("got %q, want %q", script, want)
+	}
+	if len(warnings) != 1 || warnings[0].X != 1 || warnings[0].Y != 2 {
+		t.Error(warnings)
+	}
+
+	// Coordinates which no longer fit in one instruction are given room to grow
+	script, _, err = Relocate("ff.", 1, 0)
+	if want := "1f*5+f."; err != nil || script != want {
+		t.Errorf("got %q, %v, want %q", script, err, want)
+	}
+	jump := "e0.            5n;"
+	script, _, err = Relocate(jump, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := runOutput("   " + script); got != "5" {
+		t.Errorf("relocated %q wrote %q, want \"5\"", script, got)
+	}
+
+	_, _, err = Relocate(">\"abcdefghi\"v\n    .0e     <", 2, 0)
+	if err == nil || !strings.Contains(err.Error(), "cannot make room before column 7") {
+		t.Errorf("got %v for a string crossing the inserted columns", err)
+	}
+}
@@ -0,0 +1,68 @@
+package fish
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// replSteps limits each line run by a REPL, so a line which loops forever doesn't hang it.
+const replSteps = 1000000
+
+// REPL runs lines of ><> one at a time, each as a one-line codebox which halts when the ><> reaches the end
+// of the line, starting each with the stack the last one left. It's for experimenting with instructions
+// without writing a file. "i" reads nothing, as the lines come from the REPL's input.
+type REPL struct {
+	mode  Mode
+	opts  []Option
+	stack []float64
+}
+
+// NewREPL returns a REPL which runs its first line with stack as the initial stack, and each line in mode
+// with opts.
+func NewREPL(stack []float64, mode Mode, opts ...Option) *REPL {
+	return &REPL{mode: mode, opts: opts, stack: append([]float64(nil), stack...)}
+}
+
+// Stack returns the stack left by the last line. It shouldn't be modified.
+func (r *REPL) Stack() []float64 {
+	return r.stack
+}
+
+// Eval runs line, writing its output to out. If the ><> fails or doesn't halt, Eval returns the error and
+// the stack is left as it was before the line.
+func (r *REPL) Eval(line string, out io.Writer) error {
+	opts := append([]Option{WithInput(strings.NewReader(""))}, r.opts...)
+	cB := NewCodeBox(line+";", r.stack, r.mode, append(opts, WithOutput(out))...)
+	if _, err := cB.Run(replSteps); err != nil {
+		return err
+	}
+	r.stack = append([]float64(nil), cB.Stack()...)
+	return nil
+}
+
+// Serve reads lines from in and runs them with Eval, until the end of in. After each line it writes the
+// stack, or the error, to out, followed by a prompt.
+func (r *REPL) Serve(in io.Reader, out io.Writer) error {
+	sc := bufio.NewScanner(in)
+	for fmt.Fprint(out, "><> "); sc.Scan(); fmt.Fprint(out, "><> ") {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		buf := new(strings.Builder)
+		err := r.Eval(line, buf)
+		fmt.Fprint(out, buf)
+		if buf.Len() > 0 && !strings.HasSuffix(buf.String(), "\n") {
+			fmt.Fprintln(out)
+		}
+		if err != nil {
+			fmt.Fprintln(out, "Error:", err)
+		} else {
+			fmt.Fprintln(out, "Stack:", formatStack(r.stack))
+		}
+	}
+	fmt.Fprintln(out)
+	return sc.Err()
+}
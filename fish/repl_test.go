@@ -0,0 +1,41 @@
+package fish
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestREPL(t *testing.T) {
+	r := NewREPL([]float64{1}, Spec)
+	buf := new(bytes.Buffer)
+	for _, line := range []string{"2", "3+", "::*n"} {
+		if err := r.Eval(line, buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := formatStack(r.Stack()); got != "1 5" || buf.String() != "25" {
+		t.Errorf("got stack %q and output %q", got, buf)
+	}
+	if err := r.Eval("~~~~", buf); !errors.Is(err, ErrStackEmpty) {
+		t.Errorf("got %v, want ErrStackEmpty", err)
+	}
+	if err := r.Eval("v", buf); err != ErrMaxSteps {
+		t.Errorf("got %v, want ErrMaxSteps", err)
+	}
+	if got := formatStack(r.Stack()); got != "1 5" {
+		t.Errorf("failed lines changed the stack to %q", got)
+	}
+}
+
+func TestREPLServe(t *testing.T) {
+	out := new(bytes.Buffer)
+	if err := NewREPL(nil, Spec).Serve(strings.NewReader("\"hi\"\n\nooa\n~~\n"), out); err != nil {
+		t.Fatal(err)
+	}
+	want := "><> Stack: 104 105\n><> ><> ih\nStack: 10\n><> Error: Stack is empty! ('~' at 1,0)\n><> \n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,165 @@
+package fish
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// reportHot is the number of most executed cells listed by Report.
+const reportHot = 5
+
+// reportNarrated is the number of steps narrated by Report.
+const reportNarrated = 10
+
+// firstLines is a writer which keeps the first n lines written to it and discards the rest.
+type firstLines struct {
+	n   int
+	buf bytes.Buffer
+}
+
+func (w *firstLines) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if w.n == 0 {
+			break
+		}
+		w.buf.WriteByte(b)
+		if b == '\n' {
+			w.n--
+		}
+	}
+	return len(p), nil
+}
+
+// Report runs script deterministically with input for at most maxSteps steps, and returns a Markdown
+// document describing it: its structure and likely mistakes, how the run went, its hot paths and its I/O,
+// followed by a narration of its first steps.
+func Report(script string, input []byte, maxSteps int) (string, error) {
+	p, err := Compile(script, Spec)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+
+	fmt.Fprintln(buf, "# Structure")
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "```\n%s\n```\n\n", strings.Join(splitScript(script), "\n"))
+	cB := p.New(nil)
+	var code, str, unreachable int
+	for _, row := range cB.CellKinds() {
+		for _, k := range row {
+			switch {
+			case k == 0:
+				unreachable++
+			case k&Code != 0:
+				code++
+			default:
+				str++
+			}
+		}
+	}
+	fmt.Fprintf(buf, "The codebox is %dx%d. %d cell(s) may be executed, %d only pushed as strings, and %d are "+
+		"unreachable.\n", p.width, p.height, code, str, unreachable)
+	if len(p.warnings) > 0 {
+		fmt.Fprintln(buf)
+		for _, w := range p.warnings {
+			fmt.Fprintf(buf, "* Warning: %v\n", w)
+		}
+	}
+
+	var log EventLog
+	narration := &firstLines{n: reportNarrated}
+	hits := make(map[[2]int]int)
+	depth := 0
+	cB = p.New(nil, WithInput(bytes.NewReader(input)), WithDeterministic(), WithOutput(ioutil.Discard),
+		WithEventLog(&log), WithNarration(narration))
+	cB.observers = append(cB.observers, func(x, y int) {
+		hits[[2]int{x - cB.ox, y - cB.oy}]++
+		if n := len(cB.Stack()); n > depth {
+			depth = n
+		}
+	})
+	var done bool
+	for err == nil && !done && cB.steps < maxSteps {
+		done, err = cB.Swim()
+	}
+
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "# Execution")
+	fmt.Fprintln(buf)
+	switch {
+	case err != nil:
+		fmt.Fprintf(buf, "The ><> failed after %d step(s): %v.", cB.steps, err)
+	case done:
+		fmt.Fprintf(buf, "The ><> halted after %d step(s).", cB.steps)
+	default:
+		fmt.Fprintf(buf, "The ><> didn't halt within %d steps.", maxSteps)
+	}
+	fmt.Fprintf(buf, " It executed %d distinct cell(s), and its stack held at most %d value(s).\n", len(hits),
+		depth)
+
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "# Hot paths")
+	fmt.Fprintln(buf)
+	cells := make([][2]int, 0, len(hits))
+	for c := range hits {
+		cells = append(cells, c)
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		a, b := cells[i], cells[j]
+		if hits[a] != hits[b] {
+			return hits[a] > hits[b]
+		}
+		if a[1] != b[1] {
+			return a[1] < b[1]
+		}
+		return a[0] < b[0]
+	})
+	if len(cells) > reportHot {
+		cells = cells[:reportHot]
+	}
+	fmt.Fprintln(buf, "| Cell | Instruction | Executions |")
+	fmt.Fprintln(buf, "| --- | --- | --- |")
+	for _, c := range cells {
+		r := byte(cB.get(c[0], c[1]))
+		desc, ok := descriptions[r]
+		if i := strings.IndexByte("0123456789abcdef", r); i >= 0 {
+			desc, ok = fmt.Sprintf("pushes %d", i), true
+		}
+		if ok {
+			desc = fmt.Sprintf("`%c` %s", r, desc)
+		} else {
+			desc = fmt.Sprintf("%q", r)
+		}
+		desc = strings.Replace(desc, "|", "\\|", -1)
+		fmt.Fprintf(buf, "| (%d,%d) | %s | %d |\n", c[0], c[1], desc, hits[c])
+	}
+
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "# I/O")
+	fmt.Fprintln(buf)
+	var reads, chars, nums int
+	for _, e := range log.Events {
+		switch e.Kind {
+		case InputByte:
+			reads++
+		case OutputChar:
+			chars++
+		case OutputNum:
+			nums++
+		}
+	}
+	fmt.Fprintf(buf, "The ><> read input %d time(s), and wrote %d character(s) and %d number(s).\n", reads, chars,
+		nums)
+	if out := log.Output(); out != "" {
+		fmt.Fprintf(buf, "\n```\n%s\n```\n", out)
+	}
+
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "# First steps")
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "```\n%s```\n", narration.buf.String())
+	return buf.String(), nil
+}
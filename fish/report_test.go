@@ -0,0 +1,33 @@
+package fish
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	r, err := Report(">i:0(?;o\n'", []byte("hi"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"The codebox is 8x2. 8 cell(s) may be executed, 0 only pushed as strings, and 8 are unreachable.\n",
+		"The ><> halted after 21 step(s). It executed 8 distinct cell(s), and its stack held at most 3 value(s).\n",
+		"| (0,0) | `>` makes you swim Right | 3 |\n| (1,0) | `i` reads",
+		"| (3,0) | `0` pushes 0 | 3 |\n",
+		"The ><> read input 3 time(s), and wrote 2 character(s) and 0 number(s).\n\n```\nhi\n```\n",
+		"```\n(0,0) now moving Right\n(1,0) reads",
+	} {
+		if !strings.Contains(r, want) {
+			t.Errorf("report doesn't contain %q:\n%s", want, r)
+		}
+	}
+
+	r, _ = Report("1+", nil, 100)
+	if !strings.Contains(r, "The ><> failed after 2 step(s): Stack is empty! ('+' at 1,0).") {
+		t.Errorf("report doesn't describe the failure:\n%s", r)
+	}
+	if _, err := Report("", nil, 100); err == nil {
+		t.Error("reported on an empty script")
+	}
+}
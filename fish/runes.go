@@ -0,0 +1,70 @@
+package fish
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// wideCell is stored in the codebox in place of code points above 255, which are kept in CodeBox.wide.
+const wideCell = 0xff
+
+// WithRunes lays the codebox out by character instead of by byte, so each UTF-8 character in the script,
+// such as "日", takes up a single cell holding its code point. String mode and "g" push code points, "p"
+// can store any code point, and "o" writes the character for a code point as UTF-8. Characters beyond
+// ASCII are still invalid instructions.
+func WithRunes() Option {
+	return func(cB *CodeBox) {
+		cB.runes = true
+	}
+}
+
+// layoutRunes lays the codebox out from script by character, replacing the byte layout.
+func (cB *CodeBox) layoutRunes(script string) {
+	lines := strings.Split(strings.Replace(script, "\r", "", -1), "\n")
+	rows := make([][]rune, len(lines))
+	cB.width, cB.height = 0, len(lines)
+	for i, line := range lines {
+		if rows[i] = []rune(line); len(rows[i]) > cB.width {
+			cB.width = len(rows[i])
+		}
+	}
+	cB.box, cB.shared, cB.wide = make([][]byte, cB.height), nil, make(map[[2]int]rune)
+	for y, row := range rows {
+		cB.box[y] = bytes.Repeat([]byte{' '}, cB.width)
+		for x, r := range row {
+			cB.set(x, y, r)
+		}
+	}
+}
+
+// cell returns the value of the cell at (x,y) in the codebox.
+func (cB *CodeBox) cell(x, y int) rune {
+	if r, ok := cB.wide[[2]int{x, y}]; ok {
+		return r
+	}
+	return rune(cB.box[y][x])
+}
+
+// set stores r in the cell at (x,y) in the codebox, whose row must be owned.
+func (cB *CodeBox) set(x, y int, r rune) {
+	if r > 0xff {
+		cB.wide[[2]int{x, y}] = r
+		r = wideCell
+	} else {
+		delete(cB.wide, [2]int{x, y})
+	}
+	cB.box[y][x] = byte(r)
+}
+
+// toCell converts v to a value which can be stored in the codebox: a byte, or any code point in rune mode.
+func (cB *CodeBox) toCell(v float64) rune {
+	if !cB.runes {
+		return rune(toByte(v))
+	}
+	if math.IsNaN(v) || v < 0 || v > unicode.MaxRune {
+		panic(failf(ErrCellValue, "Value %v cannot be stored in the codebox!", v))
+	}
+	return rune(v)
+}
@@ -0,0 +1,87 @@
+package fish
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWithRunes(t *testing.T) {
+	var out bytes.Buffer
+	var log EventLog
+	cB := NewCodeBox("\"日é\"oo'本'00p00g;", []float64{}, Spec, WithRunes(), WithOutput(&out), WithEventLog(&log))
+	c := cB.Clone()
+	if cB.width != 16 {
+		t.Errorf("got width %d, want 16", cB.width)
+	}
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "é日" {
+		t.Errorf("got output %q, want %q", out.String(), "é日")
+	}
+	if log.Output() != out.String() {
+		t.Errorf("the event log recorded %q, want %q", log.Output(), out.String())
+	}
+	if s := cB.Stack(); !sameValues(s, []float64{'本'}) {
+		t.Errorf("got stack %v, want [%d]", s, '本')
+	}
+	if r := c.cell(0, 0); r != '"' {
+		t.Errorf("the clone's cell (0,0) is %q, want '\"'", r)
+	}
+
+	p, err := Compile("'日';", Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cB = p.New(nil, WithRunes())
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if s := cB.Stack(); !sameValues(s, []float64{'日'}) {
+		t.Errorf("got stack %v, want [%d]", s, '日')
+	}
+
+	if cB = NewCodeBox("'日';", []float64{}, Spec); cB.width != 6 {
+		t.Errorf("got width %d without runes, want 6", cB.width)
+	}
+	out.Reset()
+	log = EventLog{}
+	if _, err = NewCodeBox("ee*9+o;", []float64{}, Spec, WithOutput(&out), WithEventLog(&log)).Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if log.Output() != out.String() {
+		t.Errorf("the event log recorded %q without runes, want %q", log.Output(), out.String())
+	}
+}
+
+func TestRunesObserved(t *testing.T) {
+	buf := new(bytes.Buffer)
+	tw := NewTraceWriter(buf, 0, false)
+	var tel Telemetry
+	cB := NewCodeBox("'本'70p;日", []float64{}, Spec, WithRunes(), WithTrace(tw), WithTelemetry(&tel))
+	before := cB.Snapshot()
+	cell, err := ParseExpr("cell(7,0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := cell(cB); v != '日' {
+		t.Errorf("cell(7,0) is %v, want %d", v, '日')
+	}
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if d := Diff(before, cB.Snapshot()); len(d.Cells) != 1 || d.Cells[0] != (CellChange{7, 0, '日', '本'}) {
+		t.Errorf("got cells %v", d.Cells)
+	}
+	if tel.Samples[1].Op != '本' {
+		t.Errorf("telemetry recorded %q", tel.Samples[1].Op)
+	}
+	tw.Close()
+	tr, err := OpenTrace(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := tr.At(1); err != nil || s.Op != '本' || !s.String {
+		t.Errorf("trace recorded %+v, %v", s, err)
+	}
+}
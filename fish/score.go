@@ -0,0 +1,62 @@
+package fish
+
+import (
+	"sort"
+	"strings"
+)
+
+// Score measures a solution to a Challenge. Lower is better in every field.
+type Score struct {
+	Bytes int // Size of the script, excluding padding
+	Steps int // Steps taken to pass every case
+	Area  int // Width times height of the codebox
+}
+
+// Entry is a ranked solution to a Challenge. Err is set if it failed the Challenge.
+type Entry struct {
+	Name string
+	Score
+	Err error
+}
+
+// Bytes returns the size of script, excluding trailing spaces on each line and trailing empty lines, which
+// only pad the codebox.
+func Bytes(script string) int {
+	lines := strings.Split(strings.Replace(script, "\r", "", -1), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	for len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return len(strings.Join(lines, "\n"))
+}
+
+// Rank checks each of the named solutions against c and returns them best first: passing solutions by
+// Bytes, then Steps, then Area, followed by failing solutions by name.
+func Rank(c *Challenge, solutions map[string]string) []Entry {
+	entries := make([]Entry, 0, len(solutions))
+	for name, script := range solutions {
+		e := Entry{Name: name, Score: Score{Bytes: Bytes(script)}}
+		if e.Steps, e.Err = c.Check(script); e.Err == nil {
+			cB := NewCodeBox(script, nil, Spec)
+			e.Area = cB.width * cB.height
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case (a.Err == nil) != (b.Err == nil):
+			return a.Err == nil
+		case a.Err != nil || a.Score == b.Score:
+			return a.Name < b.Name
+		case a.Bytes != b.Bytes:
+			return a.Bytes < b.Bytes
+		case a.Steps != b.Steps:
+			return a.Steps < b.Steps
+		}
+		return a.Area < b.Area
+	})
+	return entries
+}
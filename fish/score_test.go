@@ -0,0 +1,46 @@
+package fish
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBytes(t *testing.T) {
+	for script, want := range map[string]int{
+		"2*n;":           4,
+		"2*n;   ":        4,
+		"v  \n>n;\n\n\n": 5,
+		"\r\n":           0,
+	} {
+		if got := Bytes(script); got != want {
+			t.Errorf("%q: got %d, want %d", script, got, want)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	c, err := ReadChallenge(strings.NewReader(testChallenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := Rank(c, map[string]string{
+		"long":   "2*n ;",
+		"wrong":  "3*n;",
+		"empty":  "",
+		"short":  "2*n;",
+		"slower": ":+n;",
+	})
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if got := strings.Join(names, " "); got != "short slower long empty wrong" {
+		t.Error(got)
+	}
+	if e := entries[2]; e.Bytes != 5 || e.Steps != 10 || e.Area != 5 || e.Err != nil {
+		t.Errorf("%+v", e)
+	}
+	if entries[3].Err == nil || entries[3].Err.Error() != "script is empty" {
+		t.Error(entries[3].Err)
+	}
+}
@@ -0,0 +1,54 @@
+package fish
+
+// Match is a cell found by Search or SearchValue. Kind describes how the ><> treats the cell where it
+// matched; see CellKinds.
+type Match struct {
+	X, Y int
+	Kind CellKind
+}
+
+// literal returns the value pushed by the instruction r, and whether r pushes a value.
+func literal(r byte) (float64, bool) {
+	switch {
+	case r >= '0' && r <= '9':
+		return float64(r - '0'), true
+	case r >= 'a' && r <= 'f':
+		return float64(r - 'a' + 10), true
+	}
+	return 0, false
+}
+
+// Search returns every cell containing r, in reading order.
+func (cB *CodeBox) Search(r byte) (matches []Match) {
+	kinds := cB.CellKinds()
+	for y, line := range cB.box {
+		for x, c := range line {
+			if c == r {
+				matches = append(matches, Match{x, y, kinds[y][x]})
+			}
+		}
+	}
+	return
+}
+
+// SearchValue returns every cell which pushes v, in reading order: either a literal instruction executed as
+// code, or a character pushed as string data. Unreachable cells match if they would push v either way.
+func (cB *CodeBox) SearchValue(v float64) (matches []Match) {
+	kinds := cB.CellKinds()
+	for y, line := range cB.box {
+		for x, c := range line {
+			var kind CellKind
+			lit, ok := literal(c)
+			if kinds[y][x]&Code != 0 && ok && lit == v {
+				kind |= Code
+			}
+			if kinds[y][x]&String != 0 && float64(c) == v {
+				kind |= String
+			}
+			if kind != 0 || kinds[y][x] == 0 && (ok && lit == v || float64(c) == v) {
+				matches = append(matches, Match{x, y, kind})
+			}
+		}
+	}
+	return
+}
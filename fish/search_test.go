@@ -0,0 +1,33 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	cB := NewCodeBox("\"n\"n;\n n", []float64{}, Spec)
+	want := []Match{{1, 0, String}, {3, 0, Code}, {1, 1, 0}}
+	m := cB.Search('n')
+	if len(m) != len(want) {
+		t.Fatal(m)
+	}
+	for i := range want {
+		if m[i] != want[i] {
+			t.Errorf("match %d = %v, want %v", i, m[i], want[i])
+		}
+	}
+}
+
+func TestSearchValue(t *testing.T) {
+	cB := NewCodeBox("a\"\n\"n;\n  a", []float64{}, Spec)
+	want := []Match{{0, 0, Code}, {2, 2, 0}}
+	m := cB.SearchValue(10)
+	if len(m) != len(want) {
+		t.Fatal(m)
+	}
+	for i := range want {
+		if m[i] != want[i] {
+			t.Errorf("match %d = %v, want %v", i, m[i], want[i])
+		}
+	}
+}
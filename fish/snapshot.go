@@ -0,0 +1,150 @@
+package fish
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// Snapshot is a copy of the state of a CodeBox, taken with CodeBox.Snapshot. Positions are as seen by the
+// ><>: cell (x,y) is Box[y+OY][x+OX].
+type Snapshot struct {
+	Step       int
+	X, Y       int
+	Dir        Direction
+	StringMode byte // The quote which opened the current string, or 0
+	Box        [][]rune
+	OX, OY     int
+	Stacks     [][]float64 // Every stack, oldest first, so the current stack is last
+	Register   *float64    // The register of the current stack, if it's filled
+}
+
+// Snapshot returns a copy of the CodeBox's current state.
+func (cB *CodeBox) Snapshot() *Snapshot {
+	s := &Snapshot{Step: cB.steps, X: cB.fX - cB.ox, Y: cB.fY - cB.oy, Dir: cB.fDir, StringMode: cB.stringMode,
+		Box: make([][]rune, len(cB.box)), OX: cB.ox, OY: cB.oy, Stacks: make([][]float64, len(cB.stacks))}
+	for y, line := range cB.box {
+		s.Box[y] = make([]rune, len(line))
+		for x := range line {
+			s.Box[y][x] = cB.cell(x, y)
+		}
+	}
+	for i, st := range cB.stacks {
+		s.Stacks[i] = append([]float64(nil), st.S...)
+	}
+	if st := cB.stacks[cB.p]; st.filledRegister {
+		s.Register = new(float64)
+		*s.Register = st.register
+	}
+	return s
+}
+
+// cell returns the cell at (x,y) as seen by the ><>, or a space if the codebox hadn't grown to include it.
+func (s *Snapshot) cell(x, y int) rune {
+	x, y = x+s.OX, y+s.OY
+	if y < 0 || y >= len(s.Box) || x < 0 || x >= len(s.Box[y]) {
+		return ' '
+	}
+	return s.Box[y][x]
+}
+
+// CellChange is a cell which differs between two Snapshots.
+type CellChange struct {
+	X, Y     int
+	Old, New rune
+}
+
+// StackChange describes how a stack differs between two Snapshots: Popped are the values removed from the
+// top of the old stack, and Pushed are the values then added to make the new one. A stack which was
+// created has no Popped values, and one which was closed has no Pushed values.
+type StackChange struct {
+	Index          int // The stack's position in Snapshot.Stacks
+	Popped, Pushed []float64
+}
+
+// SnapshotDiff is what changed between two Snapshots, as returned by Diff.
+type SnapshotDiff struct {
+	Steps          int // The number of steps between the Snapshots
+	FromX, FromY   int
+	ToX, ToY       int
+	FromDir, ToDir Direction
+	Cells          []CellChange // Ordered by position, row by row
+	Stacks         []StackChange
+	Register       bool // Whether the register of the current stack changed
+}
+
+// Diff returns what changed between Snapshots a and b.
+func Diff(a, b *Snapshot) *SnapshotDiff {
+	d := &SnapshotDiff{Steps: b.Step - a.Step, FromX: a.X, FromY: a.Y, ToX: b.X, ToY: b.Y, FromDir: a.Dir,
+		ToDir: b.Dir}
+
+	// The codebox only grows, so b covers every cell a does unless they come from different CodeBoxes.
+	minX, minY := -a.OX, -a.OY
+	if -b.OX < minX {
+		minX = -b.OX
+	}
+	if -b.OY < minY {
+		minY = -b.OY
+	}
+	maxY := len(a.Box) - a.OY
+	if n := len(b.Box) - b.OY; n > maxY {
+		maxY = n
+	}
+	for y := minY; y < maxY; y++ {
+		maxX := 0
+		for _, s := range []*Snapshot{a, b} {
+			if row := y + s.OY; row >= 0 && row < len(s.Box) && len(s.Box[row])-s.OX > maxX {
+				maxX = len(s.Box[row]) - s.OX
+			}
+		}
+		for x := minX; x < maxX; x++ {
+			if o, n := a.cell(x, y), b.cell(x, y); o != n {
+				d.Cells = append(d.Cells, CellChange{x, y, o, n})
+			}
+		}
+	}
+
+	for i := 0; i < len(a.Stacks) || i < len(b.Stacks); i++ {
+		var o, n []float64
+		if i < len(a.Stacks) {
+			o = a.Stacks[i]
+		}
+		if i < len(b.Stacks) {
+			n = b.Stacks[i]
+		}
+		if i < len(a.Stacks) && i < len(b.Stacks) && sameValues(o, n) {
+			continue
+		}
+		common := 0
+		for common < len(o) && common < len(n) && math.Float64bits(o[common]) == math.Float64bits(n[common]) {
+			common++
+		}
+		d.Stacks = append(d.Stacks, StackChange{i, o[common:], n[common:]})
+	}
+	d.Register = (a.Register == nil) != (b.Register == nil) ||
+		a.Register != nil && math.Float64bits(*a.Register) != math.Float64bits(*b.Register)
+	return d
+}
+
+// Empty returns true if nothing but the number of steps changed.
+func (d *SnapshotDiff) Empty() bool {
+	return d.FromX == d.ToX && d.FromY == d.ToY && d.FromDir == d.ToDir && len(d.Cells) == 0 &&
+		len(d.Stacks) == 0 && !d.Register
+}
+
+// String returns a line describing each change.
+func (d *SnapshotDiff) String() string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "%d step(s): (%d,%d) %v -> (%d,%d) %v\n", d.Steps, d.FromX, d.FromY, d.FromDir, d.ToX,
+		d.ToY, d.ToDir)
+	for _, c := range d.Cells {
+		fmt.Fprintf(buf, "cell (%d,%d): %q -> %q\n", c.X, c.Y, c.Old, c.New)
+	}
+	for _, s := range d.Stacks {
+		fmt.Fprintf(buf, "stack %d: popped %v, pushed %v\n", s.Index, s.Popped, s.Pushed)
+	}
+	if d.Register {
+		fmt.Fprintln(buf, "register changed")
+	}
+	return buf.String()
+}
@@ -0,0 +1,48 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	cB := NewCodeBox(`5"a"10p;`, nil, Spec)
+	a := cB.Snapshot()
+	for i := 0; i < 7; i++ {
+		cB.Swim()
+	}
+	b := cB.Snapshot()
+	if d := Diff(a, a); !d.Empty() {
+		t.Errorf("a snapshot differs from itself:\n%s", d)
+	}
+	d := Diff(a, b)
+	if d.Steps != 7 || d.ToX != 7 || d.ToY != 0 || d.ToDir != Right {
+		t.Errorf("got %d steps to (%d,%d) %v, want 7 to (7,0) Right", d.Steps, d.ToX, d.ToY, d.ToDir)
+	}
+	if len(d.Cells) != 1 || d.Cells[0] != (CellChange{1, 0, '"', 'a'}) {
+		t.Errorf("got cells %v", d.Cells)
+	}
+	if len(d.Stacks) != 1 || len(d.Stacks[0].Popped) != 0 || len(d.Stacks[0].Pushed) != 1 || d.Register {
+		t.Errorf("got stacks %v, register %v", d.Stacks, d.Register)
+	}
+	want := "7 step(s): (0,0) Right -> (7,0) Right\ncell (1,0): '\"' -> 'a'\nstack 0: popped [], pushed [5]\n"
+	if d.String() != want {
+		t.Errorf("got %q, want %q", d, want)
+	}
+}
+
+func TestDiffGrown(t *testing.T) {
+	cB := NewCodeBox("121[&;", nil, Spec, WithNegativeCoordinates(NegativeGrow))
+	a := cB.Snapshot()
+	cB.put(-1, 0, 'x')
+	for i := 0; i < 5; i++ {
+		cB.Swim()
+	}
+	d := Diff(a, cB.Snapshot())
+	if len(d.Cells) != 1 || d.Cells[0] != (CellChange{-1, 0, ' ', 'x'}) {
+		t.Errorf("got cells %v", d.Cells)
+	}
+	if len(d.Stacks) != 2 || d.Stacks[0].Index != 0 || len(d.Stacks[0].Pushed) != 1 ||
+		d.Stacks[1].Index != 1 || len(d.Stacks[1].Popped) != 0 || !d.Register {
+		t.Errorf("got stacks %v, register %v", d.Stacks, d.Register)
+	}
+}
@@ -0,0 +1,80 @@
+package fish
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+)
+
+// soakHeapSlack is how much the heap may grow during a soak test before it's counted as a leak, as a
+// fraction of its size after the first round, or soakHeapMin if that's larger.
+const (
+	soakHeapSlack = 0.1
+	soakHeapMin   = 1 << 20
+)
+
+// ErrLeak is returned by Soak when resources accumulate while running ><>.
+var ErrLeak = errors.New("resources accumulated while soaking")
+
+// SoakReport describes a soak test run by Soak.
+type SoakReport struct {
+	Runs       int    // The number of ><> run
+	Goroutines [2]int // The number of goroutines after the first round and after the last
+	Heap       [2]int // The bytes allocated on the heap after the first round and after the last
+}
+
+func (r *SoakReport) String() string {
+	return fmt.Sprintf("%d runs: goroutines %d -> %d, heap %d -> %d bytes", r.Runs, r.Goroutines[0],
+		r.Goroutines[1], r.Heap[0], r.Heap[1])
+}
+
+// Soak runs each of scripts deterministically, with no input and for at most maxSteps steps, rounds times
+// over, checking that running them doesn't leak. Whether each ><> halts or fails doesn't matter. The first
+// round warms up and sets a baseline, and Soak returns an error wrapping ErrLeak if there are more
+// goroutines after the last round than after the first, or the heap has grown by more than 10% (or 1MiB).
+// It also returns an error if a script can't be loaded. opts are applied to each CodeBox.
+func Soak(scripts []string, rounds, maxSteps int, opts ...Option) (*SoakReport, error) {
+	progs := make([]*Program, len(scripts))
+	for i, script := range scripts {
+		p, err := Compile(script, Spec)
+		if err != nil {
+			return nil, err
+		}
+		progs[i] = p
+	}
+	report := new(SoakReport)
+	opts = append([]Option{WithDeterministic(), WithOutput(ioutil.Discard), WithDiagnostics(ioutil.Discard)},
+		opts...)
+	for round := 0; round < rounds; round++ {
+		for _, p := range progs {
+			p.New(nil, opts...).Run(maxSteps)
+			report.Runs++
+		}
+		if round == 0 || round == rounds-1 {
+			i := 0
+			if round > 0 {
+				i = 1
+			}
+			report.Goroutines[i], report.Heap[i] = soakUsage()
+		}
+	}
+	if rounds > 1 {
+		slack := int(float64(report.Heap[0]) * soakHeapSlack)
+		if slack < soakHeapMin {
+			slack = soakHeapMin
+		}
+		if report.Goroutines[1] > report.Goroutines[0] || report.Heap[1]-report.Heap[0] > slack {
+			return report, fmt.Errorf("%w: %v", ErrLeak, report)
+		}
+	}
+	return report, nil
+}
+
+// soakUsage collects garbage, then returns the number of goroutines and the bytes allocated on the heap.
+func soakUsage() (goroutines, heap int) {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return runtime.NumGoroutine(), int(m.HeapAlloc)
+}
@@ -0,0 +1,34 @@
+package fish
+
+import (
+	"errors"
+	"testing"
+)
+
+// leakyExtension starts a goroutine each time "L" is executed, which waits until release is closed.
+type leakyExtension struct {
+	release chan struct{}
+}
+
+func (e leakyExtension) Instructions() map[byte]Instruction {
+	return map[byte]Instruction{'L': func(cB *CodeBox) {
+		go func() { <-e.release }()
+	}}
+}
+
+func TestSoak(t *testing.T) {
+	scripts := []string{`"olleh"ooooo;`, "i:0(?;o", "1x2", "10,"}
+	report, err := Soak(scripts, 20, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Runs != 80 {
+		t.Errorf("got %d runs, want 80", report.Runs)
+	}
+
+	ext := leakyExtension{make(chan struct{})}
+	defer close(ext.release)
+	if _, err := Soak([]string{"L;"}, 5, 10, WithExtension(ext)); !errors.Is(err, ErrLeak) {
+		t.Errorf("got %v, want ErrLeak", err)
+	}
+}
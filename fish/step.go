@@ -0,0 +1,135 @@
+package fish
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrStackEmpty is the cause of a Failure when an instruction pops more values than the stack holds.
+	ErrStackEmpty = errors.New("Stack is empty!")
+	// ErrDivisionByZero is the cause of a Failure when "," or "%" divides by zero.
+	ErrDivisionByZero = errors.New("Division by zero!")
+	// ErrMaxSteps is returned by Run when the ><> doesn't halt within its step limit.
+	ErrMaxSteps = errors.New("step limit exceeded")
+	// ErrOutOfBounds is the cause of a Failure when "." jumps to a cell outside the codebox, or "g" or "p"
+	// uses a coordinate which can't be reached.
+	ErrOutOfBounds = errors.New("Jumped out of the codebox!")
+	// ErrClosed is returned when a CodeBox is used after Close.
+	ErrClosed = errors.New("CodeBox is closed")
+	// ErrOnlyStack is the cause of a Failure when "]" closes the only stack.
+	ErrOnlyStack = errors.New("Cannot close the only stack!")
+	// ErrOutOfRange is the cause of a Failure when a popped value is too large, or NaN, to be used as a
+	// coordinate, a duration or an operand; or is returned when an Option is given an invalid argument.
+	ErrOutOfRange = errors.New("value out of range")
+	// ErrCellValue is the cause of a Failure when "p" writes a value which can't be stored in the codebox.
+	ErrCellValue = errors.New("value cannot be stored in the codebox")
+	// ErrNotDivisible is the cause of a Failure when "," divides with a remainder, with WithIntegers(Exact).
+	ErrNotDivisible = errors.New("not divisible")
+	// ErrFractional is the cause of a Failure when a fractional value is pushed with WithIntegers, or is
+	// returned when one is on its initial stack.
+	ErrFractional = errors.New("fractional value in integer-only mode")
+	// ErrQuota is the cause of a Failure when the ><> exceeds a quota set with WithQuota.
+	ErrQuota = errors.New("quota exceeded")
+	// ErrAssertion is the cause of a Failure when "A" pops 0.
+	ErrAssertion = errors.New("assertion failed")
+	// ErrEmptyScript is returned when a CodeBox is given a script with no instructions.
+	ErrEmptyScript = errors.New("script is empty")
+)
+
+// causeError is an error with its own message, whose cause is one of the errors above.
+type causeError struct {
+	msg string
+	err error
+}
+
+func (e *causeError) Error() string {
+	return e.msg
+}
+
+func (e *causeError) Unwrap() error {
+	return e.err
+}
+
+// failf returns an error described by format and a, which errors.Is reports as err.
+func failf(err error, format string, a ...interface{}) error {
+	return &causeError{fmt.Sprintf(format, a...), err}
+}
+
+// ErrInvalidInstruction is the cause of a Failure when the ><> swims into a cell which isn't a built-in
+// instruction, or one provided by an enabled extension.
+type ErrInvalidInstruction struct {
+	Op   byte
+	X, Y int // The position of the cell, as seen by the ><>
+}
+
+func (e *ErrInvalidInstruction) Error() string {
+	return fmt.Sprintf("invalid instruction %q at (%d,%d)", e.Op, e.X, e.Y)
+}
+
+// Failure describes an instruction a ><> couldn't execute, as returned by Swim.
+type Failure struct {
+	X, Y   int  // The position of the instruction, as seen by the ><>
+	Op     byte // The instruction
+	Msg    string
+	Stack  []float64 // The current stack when the ><> failed
+	Origin *Origin   // Where the instruction came from, if the CodeBox has a SourceMap
+	Trace  []Frame   // The CodeBox's StackTrace when the ><> failed
+	Hint   string    // Suggestions for fixing an invalid instruction
+	Err    error     // The cause of the failure, such as ErrStackEmpty
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf("%s (%q at %d,%d)", f.Msg, f.Op, f.X, f.Y)
+}
+
+// Unwrap returns f.Err, so errors.Is and errors.As can be used to find the cause of a Failure.
+func (f *Failure) Unwrap() error {
+	return f.Err
+}
+
+// Step is Swim. It lets CodeBox implement Stepper.
+func (cB *CodeBox) Step() (done bool, err error) {
+	return cB.Swim()
+}
+
+// fail returns a *Failure for the instruction at (x,y) in the codebox, which panicked with r.
+func (cB *CodeBox) fail(x, y int, r interface{}) (f *Failure) {
+	defer func() {
+		// The CodeBox may be too broken to describe, but Swim must still return an error.
+		if r2 := recover(); r2 != nil {
+			f = &Failure{X: x - cB.ox, Y: y - cB.oy, Msg: failure(r), Err: fmt.Errorf("%v", r)}
+		}
+	}()
+	f = &Failure{X: x - cB.ox, Y: y - cB.oy, Msg: failure(r), Stack: append([]float64(nil), cB.Stack()...),
+		Trace: cB.StackTrace()}
+	if y >= 0 && y < cB.height && x >= 0 && x < cB.width {
+		f.Op = cB.box[y][x]
+	}
+	if o, ok := cB.Origin(); ok {
+		f.Origin = &o
+	}
+	if e, ok := r.(error); ok {
+		f.Err = e
+	} else {
+		f.Err = errors.New(f.Msg)
+	}
+	if e, ok := r.(*ErrInvalidInstruction); ok {
+		f.Hint = cB.diagnose(e.Op)
+	}
+	return f
+}
+
+// PrintFailure reports f, which Swim returned, to the diagnostics writer, along with the codebox.
+func (cB *CodeBox) PrintFailure(f *Failure) {
+	cB.PrintBox()
+	fmt.Fprintln(cB.diag(), "Stack:", f.Stack)
+	if f.Origin != nil {
+		fmt.Fprintln(cB.diag(), "At:", *f.Origin)
+	}
+	printStackTrace(cB.diag(), f.Trace, cB.sourceMap)
+	if f.Hint != "" {
+		fmt.Fprintln(cB.diag(), f.Hint)
+	}
+	fmt.Fprintln(cB.diag(), "something smells fishy...")
+}
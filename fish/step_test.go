@@ -0,0 +1,150 @@
+package fish
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestStep(t *testing.T) {
+	cB := NewCodeBox("1n~;", nil, Spec)
+	var err error
+	for done := false; !done && err == nil; done, err = cB.Step() {
+	}
+	f, ok := err.(*Failure)
+	if !ok || f.X != 2 || f.Y != 0 || f.Op != '~' || f.Msg != "Stack is empty!" || len(f.Stack) != 0 {
+		t.Fatal(err)
+	}
+	if want := `Stack is empty! ('~' at 2,0)`; f.Error() != want {
+		t.Errorf("got %q, want %q", f.Error(), want)
+	}
+
+	cB = NewCodeBox("1n;", nil, Spec)
+	for done := false; !done; {
+		if done, err = cB.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSwimFailure(t *testing.T) {
+	buf := new(bytes.Buffer)
+	cB := NewCodeBox("11[2[", nil, Spec, WithDiagnostics(buf))
+	var err error
+	for done := false; !done && err == nil; done, err = cB.Swim() {
+	}
+	f, ok := err.(*Failure)
+	if !ok || f.X != 4 || f.Op != '[' || len(f.Stack) != 1 || len(f.Trace) != 1 || f.Trace[0].X != 2 {
+		t.Fatalf("got %#v", err)
+	}
+	cB.PrintFailure(f)
+	want := "\n 1  1  [  2 *[*\nStack: [1]\nStack trace:\n\t[ at (2,0), step 3\nsomething smells fishy...\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf, want)
+	}
+
+	_, err = NewCodeBox("Z", nil, Spec).Swim()
+	if f, ok := err.(*Failure); !ok || f.Hint == "" {
+		t.Errorf("got %#v, want a hint for the invalid instruction", err)
+	}
+}
+
+func TestFailureCause(t *testing.T) {
+	_, err := NewCodeBox("~", nil, Spec).Swim()
+	if !errors.Is(err, ErrStackEmpty) {
+		t.Errorf("got %v, want ErrStackEmpty", err)
+	}
+	var err2 error
+	for cB, done := NewCodeBox("10%;", nil, Spec, WithIntegers(Floor)), false; !done && err2 == nil; {
+		done, err2 = cB.Swim()
+	}
+	if !errors.Is(err2, ErrDivisionByZero) {
+		t.Errorf("got %v, want ErrDivisionByZero", err2)
+	}
+	for _, script := range []string{"10,;", "10%;"} {
+		for _, mode := range []Mode{Spec, FishLanguageCom, FishInterpreterCom} {
+			if _, err := NewCodeBox(script, nil, mode).Run(0); !errors.Is(err, ErrDivisionByZero) {
+				t.Errorf("%q, %v mode: got %v, want ErrDivisionByZero", script, mode, err)
+			}
+		}
+	}
+	cB := NewCodeBox("1Z", nil, Spec)
+	var invalid *ErrInvalidInstruction
+	for err = nil; err == nil; _, err = cB.Swim() {
+	}
+	if !errors.As(err, &invalid) || *invalid != (ErrInvalidInstruction{'Z', 1, 0}) {
+		t.Errorf("got %v, want an invalid 'Z' at (1,0)", err)
+	}
+}
+
+func TestFailureCauses(t *testing.T) {
+	for _, c := range []struct {
+		script string
+		want   error
+	}{
+		{":", ErrStackEmpty},
+		{"1$", ErrStackEmpty},
+		{"12@", ErrStackEmpty},
+		{"{", ErrStackEmpty},
+		{"09.", ErrOutOfBounds},
+		{"01-0g", ErrOutOfBounds},
+		{"]", ErrOnlyStack},
+		{"ff*:*:*:*2%", ErrOutOfRange},
+		{"01-00p", ErrCellValue},
+		{"0A", ErrAssertion},
+		{"1n", ErrQuota},
+	} {
+		cB := NewCodeBox(c.script, nil, Spec, WithExtension(AssertExtension{}), WithQuota(Outputs, 0))
+		if _, err := cB.Run(100); !errors.Is(err, c.want) {
+			t.Errorf("%q: got %v, want %v", c.script, err, c.want)
+		}
+	}
+}
+
+// TestNoRuntimeErrors runs random programs made of every built-in instruction in each Mode, and fails if
+// any of them fails because of a bug in the interpreter rather than in the program.
+func TestNoRuntimeErrors(t *testing.T) {
+	const alphabet = "0123456789abcdef+-*,%=)(!?:~$@}{][lgpionr&;><^v/\\|_#x .\"'"
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		script := make([]byte, 1+rnd.Intn(40))
+		for j := range script {
+			script[j] = alphabet[rnd.Intn(len(alphabet))]
+			if j%8 == 7 {
+				script[j] = '\n'
+			}
+		}
+		for _, mode := range []Mode{Spec, FishLanguageCom, FishInterpreterCom} {
+			cB := NewCodeBox(string(script), nil, mode, WithInput(strings.NewReader("ab")),
+				WithOutput(ioutil.Discard), WithDeterministic())
+			_, err := cB.Run(500)
+			var re runtime.Error
+			if errors.As(err, &re) {
+				t.Fatalf("%q in %v mode: %v", script, mode, err)
+			}
+		}
+	}
+
+	// Coordinates far outside the codebox, which the random programs can't reach
+	huge := []float64{-1, 1 << 31, 1<<32 - 1, 1 << 53, -1 << 53, 1e300, math.Inf(-1), math.NaN()}
+	for _, op := range "pg." {
+		for _, x := range huge {
+			for _, y := range append(huge, 0) {
+				for _, mode := range []Mode{Spec, FishLanguageCom, FishInterpreterCom} {
+					cB := NewCodeBox(string(op)+";", []float64{49, x, y}, mode, WithDeterministic(),
+						WithNegativeCoordinates(NegativeGrow))
+					_, err := cB.Run(10)
+					var re runtime.Error
+					if errors.As(err, &re) {
+						t.Fatalf("%c at (%v,%v) in %v mode: %v", op, x, y, mode, err)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package fish
+
+// Store is a set of numbered slots provided by the host, which a ><> can use to persist small amounts of
+// state between runs.
+type Store interface {
+	// Load returns the value in slot, and whether slot has been filled.
+	Load(slot int) (float64, bool)
+	// Save fills slot with v.
+	Save(slot int, v float64) error
+}
+
+// MemStore is a Store held in memory.
+type MemStore map[int]float64
+
+// Load implements Store.
+func (m MemStore) Load(slot int) (float64, bool) {
+	v, ok := m[slot]
+	return v, ok
+}
+
+// Save implements Store.
+func (m MemStore) Save(slot int, v float64) error {
+	m[slot] = v
+	return nil
+}
+
+// StoreExtension is an Extension which gives a ><> access to Store. It implements "G", which pops a slot
+// and pushes its value (or 0 if it has never been filled), and "P", which pops a slot and then a value and
+// saves the value into the slot. "P" is only provided if ReadOnly isn't set.
+type StoreExtension struct {
+	Store    Store
+	ReadOnly bool
+}
+
+// Instructions implements Extension.
+func (e *StoreExtension) Instructions() map[byte]Instruction {
+	ops := map[byte]Instruction{'G': e.load}
+	if !e.ReadOnly {
+		ops['P'] = e.save
+	}
+	return ops
+}
+
+// Manifest implements Declarer. The extension needs no capabilities itself, as the host chooses where the
+// Store keeps its slots.
+func (e *StoreExtension) Manifest() Manifest {
+	if e.ReadOnly {
+		return Manifest{Name: "StoreExtension", Ops: "G"}
+	}
+	return Manifest{Name: "StoreExtension", Ops: "GP"}
+}
+
+func (e *StoreExtension) load(cB *CodeBox) {
+	v, _ := e.Store.Load(int(cB.Pop()))
+	cB.Push(v)
+}
+
+func (e *StoreExtension) save(cB *CodeBox) {
+	slot := int(cB.Pop())
+	if err := e.Store.Save(slot, cB.Pop()); err != nil {
+		panic(err)
+	}
+}
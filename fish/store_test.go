@@ -0,0 +1,22 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestStoreExtension(t *testing.T) {
+	store := MemStore{}
+	cB := NewCodeBox("P1G2G;", []float64{TESTVALUE3, 1}, Spec, WithExtension(&StoreExtension{Store: store}))
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	s := cB.Stack()
+	if len(s) != 2 || s[0] != TESTVALUE3 || s[1] != 0 || store[1] != TESTVALUE3 {
+		t.FailNow()
+	}
+
+	ext := &StoreExtension{Store: store, ReadOnly: true}
+	if _, ok := ext.Instructions()['P']; ok {
+		t.Fail()
+	}
+}
@@ -0,0 +1,61 @@
+package fish
+
+// Taint records which of a ><>'s output was derived from its input. It is experimental: only values
+// computed from input are tracked, so output chosen by branching on input ("i0=?") is not considered tainted.
+type Taint struct {
+	Outputs []bool // Whether each value written by "o" or "n" was derived from a value read by "i"
+	cells   map[[2]int]bool
+}
+
+// WithTaint tracks values read by "i" through the ><>'s stacks, register and codebox, recording in t
+// whether each value it outputs was derived from them.
+func WithTaint(t *Taint) Option {
+	return func(cB *CodeBox) {
+		t.cells = make(map[[2]int]bool)
+		cB.taint = t
+		for _, s := range cB.stacks {
+			s.taint = make([]bool, len(s.S))
+		}
+	}
+}
+
+// Depends returns true if any of the output was derived from input.
+func (t *Taint) Depends() bool {
+	for _, o := range t.Outputs {
+		if o {
+			return true
+		}
+	}
+	return false
+}
+
+// output counts an "o" or "n", and records whether the value just popped for it was tainted.
+func (cB *CodeBox) output() {
+	cB.outputs++
+	if cB.taint != nil {
+		cB.taint.Outputs = append(cB.taint.Outputs, cB.carry)
+	}
+}
+
+// pushed records the taint of the value just pushed onto s.
+func (s *Stack) pushed(t bool) {
+	if s.taint != nil {
+		s.taint = append(s.taint, t)
+	}
+}
+
+// popped removes the taint of the value just popped from s and returns it.
+func (s *Stack) popped() (t bool) {
+	if len(s.taint) > 0 {
+		t = s.taint[len(s.taint)-1]
+		s.taint = s.taint[:len(s.taint)-1]
+	}
+	return
+}
+
+// swapTaint swaps the taint of the i'th and j'th values from the end of s.
+func (s *Stack) swapTaint(i, j int) {
+	if s.taint != nil {
+		s.taint[len(s.taint)-i], s.taint[len(s.taint)-j] = s.taint[len(s.taint)-j], s.taint[len(s.taint)-i]
+	}
+}
@@ -0,0 +1,48 @@
+package fish
+
+import (
+	"strings"
+	"testing"
+)
+
+func taint(script string, stack []float64, input string) []bool {
+	var t Taint
+	cB := NewCodeBox(script, stack, Spec, WithInput(strings.NewReader(input)), WithTaint(&t))
+	for !cB.swim() {
+	}
+	return t.Outputs
+}
+
+func TestTaint(t *testing.T) {
+	for _, test := range []struct {
+		script string
+		want   []bool
+	}{
+		{"12+n;", []bool{false}},
+		{"i1+o;", []bool{true}},
+		{"i1$~n;", []bool{false}},
+		{"i12@nnn;", []bool{false, true, false}},
+		{"i&1&nn;", []bool{true, false}},
+		{"1i}nn;", []bool{false, true}},
+		{"i10p10gn;", []bool{true}},
+		{"\"ab\"i2[rnn]n;", []bool{false, true, false}},
+	} {
+		got := taint(test.script, nil, "x")
+		if len(got) != len(test.want) {
+			t.Errorf("%q: got %v, want %v", test.script, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%q: got %v, want %v", test.script, got, test.want)
+				break
+			}
+		}
+	}
+
+	var tt Taint
+	tt.Outputs = []bool{false, true}
+	if !tt.Depends() {
+		t.Error("Depends should be true")
+	}
+}
@@ -0,0 +1,64 @@
+package fish
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// Sample is the state of a ><> before it executed one step.
+type Sample struct {
+	Step   int // The number of steps executed before this one
+	X, Y   int
+	Op     rune
+	Depth  int // The length of the current stack
+	Stacks int
+}
+
+// Telemetry records a Sample for every step a ><> executes. Attach it to a CodeBox with WithTelemetry.
+type Telemetry struct {
+	Samples []Sample
+}
+
+// WithTelemetry records a Sample in t before each step.
+func WithTelemetry(t *Telemetry) Option {
+	return func(cB *CodeBox) {
+		cB.observers = append(cB.observers, func(x, y int) {
+			t.Samples = append(t.Samples, Sample{cB.steps, x, y, cB.cell(x, y), len(cB.Stack()), cB.p + 1})
+		})
+	}
+}
+
+// WriteCSV writes the Telemetry to w as CSV with a header row, for analysis in other tools.
+func (t *Telemetry) WriteCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"step", "x", "y", "op", "depth", "stacks"})
+	for _, s := range t.Samples {
+		c.Write([]string{strconv.Itoa(s.Step), strconv.Itoa(s.X), strconv.Itoa(s.Y), string(s.Op),
+			strconv.Itoa(s.Depth), strconv.Itoa(s.Stacks)})
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// WriteCSV writes the Watch's Series to w as CSV: a header row, then one row per sample, holding the step
+// followed by the value of each Series.
+func (w *Watch) WriteCSV(out io.Writer) error {
+	c := csv.NewWriter(out)
+	header := []string{"step"}
+	for _, s := range w.Series {
+		header = append(header, s.Name)
+	}
+	c.Write(header)
+	if len(w.Series) > 0 {
+		for i, step := range w.Series[0].Steps {
+			row := []string{strconv.Itoa(step)}
+			for _, s := range w.Series {
+				row = append(row, strconv.FormatFloat(s.Values[i], 'g', -1, 64))
+			}
+			c.Write(row)
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
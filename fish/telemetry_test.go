@@ -0,0 +1,32 @@
+package fish
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTelemetry(t *testing.T) {
+	var tel Telemetry
+	w := NewWatch(2)
+	w.Add("depth", StackDepth)
+	w.Add("cell", Cell(0, 0))
+	cB := NewCodeBox("11[2;", nil, Spec, WithTelemetry(&tel), WithWatch(w))
+	for !cB.swim() {
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tel.WriteCSV(buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "step,x,y,op,depth,stacks\n0,0,0,1,0,1\n1,1,0,1,1,1\n2,2,0,[,2,1\n3,3,0,2,1,2\n4,4,0,;,2,2\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf, want)
+	}
+
+	buf.Reset()
+	if err := w.WriteCSV(buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "step,depth,cell\n0,0,49\n2,2,49\n4,2,49\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf, want)
+	}
+}
@@ -0,0 +1,112 @@
+package fish
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholder matches a placeholder in a Template.
+var placeholder = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Template is a ><> script containing placeholders such as {N}, which Execute replaces with code pushing
+// values supplied by the host. As "{" and "}" are also instructions, only placeholders named in the values
+// given to Execute are replaced, so code such as "{l}" is left alone unless a value named "l" is supplied.
+//
+// Replacing a placeholder changes the width of its row, moving any cells after it, so placeholders are best
+// put where that doesn't matter, such as at the end of a row or on a row of their own.
+type Template string
+
+// Execute returns the script with each placeholder replaced by code which pushes the value of the same name.
+// Values may be integers and floats with integer values, up to math.MaxInt32 either way, bools (pushed as 1 or 0), or strings (pushed a
+// character at a time, first character first). It returns an error if a value can't be pushed, or has no
+// placeholder.
+func (t Template) Execute(values map[string]interface{}) (string, error) {
+	code := make(map[string]string, len(values))
+	for name, v := range values {
+		c, err := pushGo(v)
+		if err != nil {
+			return "", fmt.Errorf("template: {%s}: %v", name, err)
+		}
+		code[name] = c
+	}
+	used := make(map[string]bool, len(values))
+	script := placeholder.ReplaceAllStringFunc(string(t), func(p string) string {
+		name := p[1 : len(p)-1]
+		if c, ok := code[name]; ok {
+			used[name] = true
+			return c
+		}
+		return p
+	})
+	var unused []string
+	for name := range values {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return "", fmt.Errorf("template: no placeholder for %s", strings.Join(unused, ", "))
+	}
+	return script, nil
+}
+
+// pushGo returns ><> code which pushes the Go value v. Numbers must be integers which pushValue can push.
+func pushGo(v interface{}) (string, error) {
+	var n float64
+	switch v := v.(type) {
+	case int:
+		n = float64(v)
+	case int8:
+		n = float64(v)
+	case int16:
+		n = float64(v)
+	case int32:
+		n = float64(v)
+	case int64:
+		n = float64(v)
+	case uint8:
+		n = float64(v)
+	case uint16:
+		n = float64(v)
+	case uint32:
+		n = float64(v)
+	case float64:
+		if v != math.Trunc(v) || math.IsInf(v, 0) {
+			return "", fmt.Errorf("%v isn't an integer", v)
+		}
+		n = v
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case string:
+		return pushText(v), nil
+	default:
+		return "", fmt.Errorf("can't push a %T", v)
+	}
+	if !bakeable([]float64{n}) {
+		return "", fmt.Errorf("%v is out of range (at most %d either way)", v, math.MaxInt32)
+	}
+	return pushValue(int(n)), nil
+}
+
+// pushText returns ><> code which pushes each byte of s in turn, as a string literal if possible.
+func pushText(s string) string {
+	if !strings.ContainsAny(s, "\n\r") {
+		for _, q := range []string{`"`, "'"} {
+			if !strings.Contains(s, q) {
+				return q + s + q
+			}
+		}
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		b.WriteString(pushInt(int(s[i])))
+	}
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package fish
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	script, err := Template("{N}{l}{M}{S};").Execute(map[string]interface{}{
+		"N": -20,
+		"M": 3.0,
+		"S": "a\"b",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cB := NewCodeBox(script, nil, Spec)
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	// {l} is left as code: it pushes the length of the stack, then rotates it to the bottom.
+	want := []float64{1, -20, 3, 'a', '"', 'b'}
+	if s := cB.Stack(); !sameValues(s, want) {
+		t.Errorf("got stack %v, want %v", s, want)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	for _, values := range []map[string]interface{}{
+		{"N": 1.5},
+		{"N": 1e20},
+		{"N": int64(math.MinInt64)},
+		{"N": uint32(math.MaxUint32)},
+		{"N": []int{1}},
+		{"N": 1, "M": 2},
+	} {
+		if _, err := Template("{N};").Execute(values); err == nil {
+			t.Errorf("%v: expected an error", values)
+		}
+	}
+	_, err := Template("{N};").Execute(map[string]interface{}{"N": 1e20})
+	want := "template: {N}: 1e+20 is out of range (at most 2147483647 either way)"
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v, want %q", err, want)
+	}
+}
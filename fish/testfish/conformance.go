@@ -0,0 +1,142 @@
+package testfish
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/redstarcoder/go-fish/fish"
+	"io/ioutil"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// awkwardStacks are the stacks each instruction of an extension is executed with by CheckExtension.
+var awkwardStacks = [][]float64{nil, {0}, {-1}, {0.5}, {math.NaN()}, {math.Inf(1)}, {1e300}, {3, 2, 1}}
+
+// outcome is how a run by CheckExtension ended.
+type outcome struct {
+	out   string
+	stack []float64
+	steps int
+	err   string
+}
+
+func (o outcome) String() string {
+	return fmt.Sprintf("output %q, stack %v after %d steps, error %q", o.out, o.stack, o.steps, o.err)
+}
+
+func (o outcome) equal(o2 outcome) bool {
+	return o.out == o2.out && stackDiff(o.stack, o2.stack) == "" && o.steps == o2.steps && o.err == o2.err
+}
+
+// conform runs script with ext and input, and the extra opts, for at most expectSteps steps.
+func conform(ext fish.Extension, script, input string, opts ...fish.Option) (o outcome) {
+	buf := new(bytes.Buffer)
+	cB := fish.NewCodeBox(script, nil, fish.Spec, append([]fish.Option{fish.WithDeterministic(),
+		fish.WithDiagnostics(ioutil.Discard), fish.WithInput(strings.NewReader(input)), fish.WithExtension(ext),
+		fish.WithOutput(buf)}, opts...)...)
+	var err error
+	o.steps, err = cB.Run(expectSteps)
+	if err != nil {
+		o.err = err.Error()
+	}
+	o.out, o.stack = buf.String(), cB.Stack()
+	return o
+}
+
+// CheckExtension reports an error to t for each way in which ext could break the tools built on the fish
+// package, such as tracing, time travel, and the judge. It's meant for authors of extensions, to be run
+// with scripts which exercise their instructions, each given input. It checks that:
+//
+//   - ext declares a truthful fish.Manifest, and none of its instructions is built in
+//   - each instruction fails cleanly, without a runtime error, given awkward stacks
+//   - each script behaves the same when run twice, so the extension keeps no hidden state and doesn't use
+//     the clock or randomness except through the CodeBox
+//   - each script behaves the same when its input is replayed from a fish.EventLog, so the extension reads
+//     input only through the CodeBox
+//   - a trace of each script records every step
+//   - a clone of the CodeBox made halfway through each script finishes as the original did
+func CheckExtension(t T, ext fish.Extension, input string, scripts ...string) {
+	t.Helper()
+	m, err := fish.Approve(ext, fish.Filesystem, fish.Network, fish.Clock)
+	if err != nil {
+		t.Errorf("manifest: %v", err)
+	}
+	var ops []byte
+	for op := range ext.Instructions() {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i] < ops[j] })
+	for _, op := range ops {
+		_, err := fish.NewCodeBox(string(op), nil, fish.Spec).Swim()
+		var invalid *fish.ErrInvalidInstruction
+		if !errors.As(err, &invalid) {
+			t.Errorf("manifest: %s: %q is built in, so can't be provided by an extension", m.Name, op)
+			continue
+		}
+		for _, s := range awkwardStacks {
+			cB := fish.NewCodeBox(string(op)+";", s, fish.Spec, fish.WithDeterministic(), fish.WithExtension(ext),
+				fish.WithInput(strings.NewReader("")), fish.WithOutput(ioutil.Discard),
+				fish.WithDiagnostics(ioutil.Discard))
+			_, err := cB.Run(expectSteps)
+			var re runtime.Error
+			if errors.As(err, &re) {
+				t.Errorf("errors: %q with stack %v: %v", op, s, err)
+			}
+		}
+	}
+
+	for _, script := range scripts {
+		want := conform(ext, script, input)
+		if o := conform(ext, script, input); !o.equal(want) {
+			t.Errorf("determinism: %q: first run gave %v, second gave %v", script, want, o)
+		}
+
+		var log fish.EventLog
+		conform(ext, script, input, fish.WithEventLog(&log))
+		if o := conform(ext, script, "", log.Replay()); !o.equal(want) {
+			t.Errorf("replay: %q: run gave %v, replay gave %v", script, want, o)
+		}
+
+		buf := new(bytes.Buffer)
+		tw := fish.NewTraceWriter(buf, 64, false)
+		conform(ext, script, input, fish.WithTrace(tw))
+		if err := tw.Close(); err != nil {
+			t.Errorf("trace: %q: %v", script, err)
+		} else if tr, err := fish.OpenTrace(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+			t.Errorf("trace: %q: %v", script, err)
+		} else if tr.Len() != want.steps {
+			t.Errorf("trace: %q: recorded %d steps, want %d", script, tr.Len(), want.steps)
+		}
+
+		checkClone(t, ext, script, input, want)
+	}
+}
+
+// checkClone runs script halfway, then clones the CodeBox and checks the clone finishes as want did.
+func checkClone(t T, ext fish.Extension, script, input string, want outcome) {
+	t.Helper()
+	half := want.steps / 2
+	buf := new(bytes.Buffer)
+	cB := fish.NewCodeBox(script, nil, fish.Spec, fish.WithDeterministic(), fish.WithDiagnostics(ioutil.Discard),
+		fish.WithInput(strings.NewReader(input)), fish.WithExtension(ext), fish.WithOutput(buf))
+	if half > 0 {
+		if _, err := cB.Run(half); err != nil && err != fish.ErrMaxSteps {
+			t.Errorf("clone: %q: failed before cloning: %v", script, err)
+			return
+		}
+	}
+	before := buf.String()
+	clone := cB.Clone(fish.WithOutput(buf))
+	steps, err := clone.Run(expectSteps)
+	o := outcome{out: buf.String(), stack: clone.Stack(), steps: half + steps}
+	if err != nil {
+		o.err = err.Error()
+	}
+	if !o.equal(want) {
+		t.Errorf("clone: %q: run gave %v, clone made after %d steps and output %q gave %v", script, want, half,
+			before, o)
+	}
+}
@@ -0,0 +1,76 @@
+package testfish
+
+import (
+	"github.com/redstarcoder/go-fish/fish"
+	"strings"
+	"testing"
+)
+
+func TestCheckExtensionBuiltins(t *testing.T) {
+	CheckExtension(t, fish.CallExtension{}, "", "01C01Cn;\n>:+R", "R")
+	CheckExtension(t, fish.NumberInput{}, "12 -3.5 x", "I?!;n10.")
+	CheckExtension(t, fish.AssertExtension{}, "", "1A0A;")
+	CheckExtension(t, fish.Timing{}, "", "hms++n5S;")
+}
+
+// counter keeps hidden state, so runs differ.
+type counter struct{ n float64 }
+
+func (c *counter) Instructions() map[byte]fish.Instruction {
+	return map[byte]fish.Instruction{'K': func(cB *fish.CodeBox) {
+		c.n++
+		cB.Push(c.n)
+	}}
+}
+
+func (c *counter) Manifest() fish.Manifest {
+	return fish.Manifest{Name: "counter", Ops: "K"}
+}
+
+// reader reads input behind the CodeBox's back, so replays differ.
+type reader struct{ r *strings.Reader }
+
+func (e reader) Instructions() map[byte]fish.Instruction {
+	return map[byte]fish.Instruction{'Y': func(cB *fish.CodeBox) {
+		b, _ := e.r.ReadByte()
+		cB.Push(float64(b))
+	}}
+}
+
+// sloppy indexes the stack without checking it, and claims "n", which is built in.
+type sloppy struct{}
+
+func (sloppy) Instructions() map[byte]fish.Instruction {
+	return map[byte]fish.Instruction{'S': func(cB *fish.CodeBox) {
+		cB.Push(cB.Stack()[0])
+	}, 'n': func(*fish.CodeBox) {}}
+}
+
+func (sloppy) Manifest() fish.Manifest {
+	return fish.Manifest{Name: "sloppy", Ops: "Sn"}
+}
+
+func TestCheckExtension(t *testing.T) {
+	for _, c := range []struct {
+		ext    fish.Extension
+		script string
+		want   []string // The checks which should fail
+	}{
+		{&counter{}, "KKn;", []string{"determinism", "replay", "clone"}},
+		{reader{strings.NewReader(strings.Repeat("ab", 20))}, "Yn;", []string{"manifest", "determinism", "replay", "clone"}},
+		{sloppy{}, "1S+n;", []string{"errors", "manifest"}},
+	} {
+		r := new(recorder)
+		CheckExtension(r, c.ext, "", c.script)
+		var got []string
+		for _, e := range r.errs {
+			check := e[:strings.Index(e, ":")]
+			if len(got) == 0 || got[len(got)-1] != check {
+				got = append(got, check)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(c.want, ",") {
+			t.Errorf("%T: got failed checks %v, want %v; errors were %q", c.ext, got, c.want, r.errs)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package testfish
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/redstarcoder/go-fish/fish"
+	"io/ioutil"
+)
+
+// expectSteps limits the runs made by ExpectOutput and ExpectStack, so a ><> which never halts fails the
+// test instead of hanging it.
+const expectSteps = 1000000
+
+// diffContext is the number of bytes of output shown either side of the first difference.
+const diffContext = 16
+
+// T is the part of testing.TB used to report failures.
+type T interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// run runs script deterministically for at most maxSteps steps, returning the CodeBox, its output, and the
+// number of steps taken.
+func run(script string, maxSteps int, opts []fish.Option) (cB *fish.CodeBox, out string, steps int, err error) {
+	buf := new(bytes.Buffer)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	cB = fish.NewCodeBox(script, nil, fish.Spec, append([]fish.Option{fish.WithDeterministic(),
+		fish.WithDiagnostics(ioutil.Discard)}, append(opts, fish.WithOutput(buf))...)...)
+	steps, err = cB.Run(maxSteps)
+	return cB, buf.String(), steps, err
+}
+
+// ExpectOutput runs script deterministically with opts, such as fish.WithInput, and reports an error to t
+// unless the ><> halts having written exactly want. The error shows where the output first differs.
+func ExpectOutput(t T, script, want string, opts ...fish.Option) {
+	t.Helper()
+	_, got, _, err := run(script, expectSteps, opts)
+	if err != nil {
+		t.Errorf("%v, after writing %q", err, got)
+	} else if d := outputDiff(got, want); d != "" {
+		t.Errorf("%s", d)
+	}
+}
+
+// ExpectStack runs script deterministically with opts, and reports an error to t unless the ><> halts
+// with exactly want on its stack. The error shows where the stack first differs.
+func ExpectStack(t T, script string, want []float64, opts ...fish.Option) {
+	t.Helper()
+	cB, _, _, err := run(script, expectSteps, opts)
+	if err != nil {
+		t.Errorf("%v", err)
+	} else if d := stackDiff(cB.Stack(), want); d != "" {
+		t.Errorf("%s", d)
+	}
+}
+
+// ExpectHaltWithin runs script deterministically with opts, and reports an error to t unless the ><>
+// halts successfully within maxSteps steps. It returns the number of steps taken.
+func ExpectHaltWithin(t T, script string, maxSteps int, opts ...fish.Option) int {
+	t.Helper()
+	cB, _, steps, err := run(script, maxSteps, opts)
+	if err == fish.ErrMaxSteps {
+		x, y := cB.Position()
+		t.Errorf("didn't halt within %d steps, and was at (%d,%d)", maxSteps, x, y)
+	} else if err != nil {
+		t.Errorf("%v, after %d steps", err, steps)
+	}
+	return steps
+}
+
+// outputDiff describes the first difference between got and want, or returns "" if they're the same.
+func outputDiff(got, want string) string {
+	if got == want {
+		return ""
+	}
+	i := 0
+	for i < len(got) && i < len(want) && got[i] == want[i] {
+		i++
+	}
+	start := i - diffContext
+	if start < 0 {
+		start = 0
+	}
+	window := func(s string) string {
+		end := i + diffContext
+		if end > len(s) {
+			end = len(s)
+		}
+		w := fmt.Sprintf("%q", s[start:end])
+		if start > 0 {
+			w = "..." + w
+		}
+		if end < len(s) {
+			w += "..."
+		}
+		return w
+	}
+	return fmt.Sprintf("output differs at byte %d (got %d bytes, want %d):\n got: %s\nwant: %s", i, len(got),
+		len(want), window(got), window(want))
+}
+
+// stackDiff describes the first difference between got and want, or returns "" if they're the same.
+func stackDiff(got, want []float64) string {
+	i := 0
+	for i < len(got) && i < len(want) && got[i] == want[i] {
+		i++
+	}
+	if i == len(got) && i == len(want) {
+		return ""
+	}
+	return fmt.Sprintf("stack differs at index %d (got %d values, want %d):\n got: %v\nwant: %v", i, len(got),
+		len(want), got, want)
+}
@@ -0,0 +1,68 @@
+package testfish
+
+import (
+	"fmt"
+	"github.com/redstarcoder/go-fish/fish"
+	"strings"
+	"testing"
+)
+
+// recorder is a T which records the errors reported to it.
+type recorder struct {
+	errs []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestExpectOutput(t *testing.T) {
+	ExpectOutput(t, `i:0(?;o`, "echo", fish.WithInput(strings.NewReader("echo")))
+
+	r := new(recorder)
+	ExpectOutput(r, `"olleh"ooooo;`, "help")
+	ExpectOutput(r, `"a"on;`, "a")
+	want := []string{
+		"output differs at byte 3 (got 5 bytes, want 4):\n got: \"hello\"\nwant: \"help\"",
+		"Stack is empty! ('n' at 4,0), after writing \"a\"",
+	}
+	if strings.Join(r.errs, "|") != strings.Join(want, "|") {
+		t.Errorf("got errors %q, want %q", r.errs, want)
+	}
+}
+
+func TestExpectStack(t *testing.T) {
+	ExpectStack(t, "12+3;", []float64{3, 3})
+
+	r := new(recorder)
+	ExpectStack(r, "123;", []float64{1, 3})
+	want := "stack differs at index 1 (got 3 values, want 2):\n got: [1 2 3]\nwant: [1 3]"
+	if len(r.errs) != 1 || r.errs[0] != want {
+		t.Errorf("got errors %q, want %q", r.errs, want)
+	}
+}
+
+func TestExpectHaltWithin(t *testing.T) {
+	if steps := ExpectHaltWithin(t, "1;", 2); steps != 2 {
+		t.Errorf("got %d steps, want 2", steps)
+	}
+
+	r := new(recorder)
+	ExpectHaltWithin(r, "1>", 10)
+	want := "didn't halt within 10 steps, and was at (0,0)"
+	if len(r.errs) != 1 || r.errs[0] != want {
+		t.Errorf("got errors %q, want %q", r.errs, want)
+	}
+}
+
+func TestOutputDiff(t *testing.T) {
+	got := strings.Repeat("a", 40) + "b" + strings.Repeat("c", 40)
+	want := strings.Repeat("a", 40) + "c"
+	d := outputDiff(got, want)
+	if !strings.Contains(d, `got: ..."aaaaaaaaaaaaaaaabccccccccccccccc"...`) ||
+		!strings.Contains(d, `want: ..."aaaaaaaaaaaaaaaac"`) {
+		t.Error(d)
+	}
+}
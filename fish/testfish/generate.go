@@ -0,0 +1,78 @@
+package testfish
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+)
+
+// Sizes of the values generated, which are also bounded by the size testing/quick asks for.
+const (
+	maxProgramWidth  = 8
+	maxProgramHeight = 4
+)
+
+// programAlphabet is the instructions generated programs are made of.
+const programAlphabet = "0123456789abcdef+-*,%=)(!?:~$@}{r[]lgpion&;><^v/\\|_#x .\"'"
+
+// Stack is an initial stack for property-based tests, which generates itself for testing/quick as up to
+// size integers between -size and size. For example, to check a sort routine:
+//
+//	quick.Check(func(s testfish.Stack) bool { ... fish.NewCodeBox(sort, s, fish.Spec) ... }, nil)
+type Stack []float64
+
+// Generate implements quick.Generator.
+func (Stack) Generate(r *rand.Rand, size int) reflect.Value {
+	s := make(Stack, r.Intn(size+1))
+	for i := range s {
+		s[i] = float64(r.Intn(2*size+1) - size)
+	}
+	return reflect.ValueOf(s)
+}
+
+// Input is input for property-based tests, which generates itself for testing/quick as up to size bytes
+// of printable ASCII and newlines.
+type Input []byte
+
+// Generate implements quick.Generator.
+func (Input) Generate(r *rand.Rand, size int) reflect.Value {
+	in := make(Input, r.Intn(size+1))
+	for i := range in {
+		if r.Intn(16) == 0 {
+			in[i] = '\n'
+		} else {
+			in[i] = byte(' ' + r.Intn('~'-' '+1))
+		}
+	}
+	return reflect.ValueOf(in)
+}
+
+// Program is a small ><> script for property-based tests, such as checking that a host copes with
+// whatever a ><> does. It generates itself for testing/quick as a rectangle of random instructions, at
+// most 8 wide and 4 high. Most generated programs fail or never halt, so they should be run with a step
+// limit.
+type Program string
+
+// Generate implements quick.Generator.
+func (Program) Generate(r *rand.Rand, size int) reflect.Value {
+	w, h := 1+r.Intn(bound(size, maxProgramWidth)), 1+r.Intn(bound(size, maxProgramHeight))
+	rows := make([]string, h)
+	for y := range rows {
+		row := make([]byte, w)
+		for x := range row {
+			row[x] = programAlphabet[r.Intn(len(programAlphabet))]
+		}
+		rows[y] = string(row)
+	}
+	return reflect.ValueOf(Program(strings.Join(rows, "\n")))
+}
+
+// bound returns size limited to between 1 and max.
+func bound(size, max int) int {
+	if size > max {
+		return max
+	} else if size < 1 {
+		return 1
+	}
+	return size
+}
@@ -0,0 +1,53 @@
+package testfish
+
+import (
+	"github.com/redstarcoder/go-fish/fish"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestGenerateStack(t *testing.T) {
+	reverses := func(s Stack) bool {
+		cB := fish.NewCodeBox("r;", s, fish.Spec)
+		if _, err := cB.Run(0); err != nil {
+			return false
+		}
+		got := cB.Stack()
+		for i := range s {
+			if got[i] != s[len(s)-1-i] {
+				return false
+			}
+		}
+		return len(got) == len(s)
+	}
+	if err := quick.Check(reverses, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGenerateInput(t *testing.T) {
+	echoes := func(in Input) bool {
+		r := new(recorder)
+		ExpectOutput(r, "i:0(?;o", string(in), fish.WithInput(strings.NewReader(string(in))))
+		return len(r.errs) == 0
+	}
+	if err := quick.Check(echoes, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGenerateProgram(t *testing.T) {
+	fits := func(p Program) bool {
+		rows := strings.Split(string(p), "\n")
+		if len(rows) > maxProgramHeight || len(rows[0]) > maxProgramWidth {
+			return false
+		}
+		cB := fish.NewCodeBox(string(p), nil, fish.Spec, fish.WithDeterministic(), fish.WithOutput(new(strings.Builder)))
+		cB.Run(100)
+		return true
+	}
+	if err := quick.Check(fits, nil); err != nil {
+		t.Error(err)
+	}
+}
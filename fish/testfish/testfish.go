@@ -0,0 +1,113 @@
+// Package testfish provides a fake ><> interpreter, so applications which embed package fish can be unit
+// tested without running real ><> code, matchers for testing real ><> code from Go, and a conformance kit
+// for extensions.
+package testfish
+
+import (
+	"bytes"
+	"github.com/redstarcoder/go-fish/fish"
+	"io"
+)
+
+// Tick is a single scripted tick of a Fish.
+type Tick struct {
+	Output string         // Written by the Fish when the tick is executed
+	Stack  []float64      // The stack after the tick, or nil to leave it unchanged
+	X, Y   int            // The position of the ><> after the tick
+	Dir    fish.Direction // The direction of the ><> after the tick
+	Err    error          // If set, Step returns Err instead of executing the tick
+}
+
+// Fish is a scripted fake implementing fish.Runner, fish.Stepper and fish.Inspector. Each call to Step
+// executes the next of its Ticks, and it halts after executing the last one.
+type Fish struct {
+	Ticks []Tick
+	W     io.Writer // If set, output is also written to W as it's produced
+
+	out   bytes.Buffer
+	steps int
+	tick  Tick
+}
+
+var (
+	_ fish.Runner    = (*Fish)(nil)
+	_ fish.Stepper   = (*Fish)(nil)
+	_ fish.Inspector = (*Fish)(nil)
+)
+
+// New returns a Fish which executes ticks.
+func New(ticks ...Tick) *Fish {
+	return &Fish{Ticks: ticks}
+}
+
+// Printing returns a Fish which writes output, then halts with stack on its stack.
+func Printing(output string, stack ...float64) *Fish {
+	return New(Tick{Output: output, Stack: stack})
+}
+
+// Failing returns a Fish which fails with err on its first tick.
+func Failing(err error) *Fish {
+	return New(Tick{Err: err})
+}
+
+// Step executes the next tick. Once the Fish has failed, every call returns the same error.
+func (f *Fish) Step() (done bool, err error) {
+	if f.steps >= len(f.Ticks) {
+		return true, nil
+	}
+	t := f.Ticks[f.steps]
+	if t.Err != nil {
+		return false, t.Err
+	}
+	f.out.WriteString(t.Output)
+	if f.W != nil {
+		if _, err := io.WriteString(f.W, t.Output); err != nil {
+			return false, err
+		}
+	}
+	if t.Stack == nil {
+		t.Stack = f.tick.Stack
+	}
+	f.tick = t
+	f.steps++
+	return f.steps == len(f.Ticks), nil
+}
+
+// Run calls Step until the Fish halts or fails, or until it has taken maxSteps steps if maxSteps is
+// positive, in which case it returns fish.ErrMaxSteps.
+func (f *Fish) Run(maxSteps int) (steps int, err error) {
+	for done := false; !done; steps++ {
+		if maxSteps > 0 && steps == maxSteps {
+			return steps, fish.ErrMaxSteps
+		}
+		if done, err = f.Step(); err != nil {
+			return steps + 1, err
+		}
+	}
+	return steps, nil
+}
+
+// Output returns everything the Fish has written so far.
+func (f *Fish) Output() string {
+	return f.out.String()
+}
+
+// Stack returns the stack set by the last tick which set one.
+func (f *Fish) Stack() []float64 {
+	return f.tick.Stack
+}
+
+// Position returns the position set by the last tick.
+func (f *Fish) Position() (x, y int) {
+	return f.tick.X, f.tick.Y
+}
+
+// Direction returns the direction set by the last tick.
+func (f *Fish) Direction() fish.Direction {
+	return f.tick.Dir
+}
+
+// Steps returns the number of ticks executed so far.
+func (f *Fish) Steps() int {
+	return f.steps
+}
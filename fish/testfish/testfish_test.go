@@ -0,0 +1,76 @@
+package testfish
+
+import (
+	"bytes"
+	"errors"
+	"github.com/redstarcoder/go-fish/fish"
+	"testing"
+)
+
+// runAll is an example of host code which only depends on fish.Runner.
+func runAll(r fish.Runner) error {
+	_, err := r.Run(0)
+	return err
+}
+
+func TestPrinting(t *testing.T) {
+	var w bytes.Buffer
+	f := Printing("hi", 1, 2)
+	f.W = &w
+	if err := runAll(f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Output() != "hi" || w.String() != "hi" {
+		t.Errorf("got output %q and %q, want %q", f.Output(), w.String(), "hi")
+	}
+	if s := f.Stack(); len(s) != 2 || s[0] != 1 || s[1] != 2 {
+		t.Errorf("got stack %v, want [1 2]", s)
+	}
+	if done, err := f.Step(); !done || err != nil {
+		t.Errorf("got %v, %v after halting, want true, nil", done, err)
+	}
+}
+
+func TestFailing(t *testing.T) {
+	want := &fish.Failure{X: 1, Y: 0, Op: '+', Msg: "Stack is empty!"}
+	f := New(Tick{Output: "a"}, Tick{Err: want}, Tick{Output: "b"})
+	if err := runAll(f); err != want {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+	if _, err := f.Step(); err != want {
+		t.Errorf("got %v after failing, want %v", err, want)
+	}
+	if f.Output() != "a" || f.Steps() != 1 {
+		t.Errorf("got output %q after %d steps, want %q after 1", f.Output(), f.Steps(), "a")
+	}
+	if err := runAll(Failing(errors.New("boom"))); err == nil || err.Error() != "boom" {
+		t.Errorf("got %v, want boom", err)
+	}
+}
+
+func TestTicks(t *testing.T) {
+	f := New(Tick{Stack: []float64{1}, X: 1, Dir: fish.Down}, Tick{X: 1, Y: 1, Dir: fish.Down})
+	var i fish.Inspector = f
+	if done, err := f.Step(); done || err != nil {
+		t.Fatalf("got %v, %v, want false, nil", done, err)
+	}
+	if done, err := f.Step(); !done || err != nil {
+		t.Fatalf("got %v, %v, want true, nil", done, err)
+	}
+	if x, y := i.Position(); x != 1 || y != 1 || i.Direction() != fish.Down {
+		t.Errorf("got (%d,%d) %v, want (1,1) %v", x, y, i.Direction(), fish.Down)
+	}
+	if s := i.Stack(); len(s) != 1 || s[0] != 1 || i.Steps() != 2 {
+		t.Errorf("got stack %v after %d steps, want [1] after 2", s, i.Steps())
+	}
+}
+
+func TestRunMaxSteps(t *testing.T) {
+	f := New(Tick{}, Tick{}, Tick{})
+	if n, err := f.Run(2); n != 2 || err != fish.ErrMaxSteps {
+		t.Errorf("got %d, %v, want 2, ErrMaxSteps", n, err)
+	}
+	if n, err := f.Run(2); n != 1 || err != nil {
+		t.Errorf("got %d, %v resuming, want 1, nil", n, err)
+	}
+}
@@ -0,0 +1,73 @@
+package fish
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// Colours used by WriteThumbnail for each class of cell.
+var (
+	thumbWater   = color.RGBA{0xf4, 0xf8, 0xfb, 0xff} // Empty cells
+	thumbMove    = color.RGBA{0x1f, 0x77, 0xb4, 0xff} // Arrows, mirrors, trampolines and jumps
+	thumbMath    = color.RGBA{0xff, 0x7f, 0x0e, 0xff} // Arithmetic and comparisons
+	thumbStack   = color.RGBA{0x2c, 0xa0, 0x2c, 0xff} // Stack and register manipulation
+	thumbIO      = color.RGBA{0xd6, 0x27, 0x28, 0xff} // Input and output
+	thumbBox     = color.RGBA{0x94, 0x67, 0xbd, 0xff} // Reading and writing the codebox
+	thumbLiteral = color.RGBA{0x8c, 0x56, 0x4b, 0xff} // Digits
+	thumbString  = color.RGBA{0xbc, 0xbd, 0x22, 0xff} // Quotes, and cells only pushed as strings
+	thumbHalt    = color.RGBA{0x00, 0x00, 0x00, 0xff}
+	thumbOther   = color.RGBA{0x7f, 0x7f, 0x7f, 0xff} // Unreachable cells, and extension or invalid instructions
+)
+
+// thumbColour returns the colour of the cell r, which has kind k.
+func thumbColour(r byte, k CellKind) color.Color {
+	switch {
+	case r == ' ':
+		return thumbWater
+	case k == 0:
+		return thumbOther
+	case r == '"' || r == '\'' || k == String:
+		return thumbString
+	case r == ';':
+		return thumbHalt
+	case strings.IndexByte("><^v/\\|_#x!?.", r) >= 0:
+		return thumbMove
+	case strings.IndexByte("+-*,%=()", r) >= 0:
+		return thumbMath
+	case strings.IndexByte(":~$@}{r[]l&", r) >= 0:
+		return thumbStack
+	case strings.IndexByte("ion", r) >= 0:
+		return thumbIO
+	case r == 'g' || r == 'p':
+		return thumbBox
+	case strings.IndexByte("0123456789abcdef", r) >= 0:
+		return thumbLiteral
+	}
+	return thumbOther
+}
+
+// WriteThumbnail renders the codebox to w as a PNG, with each cell drawn as a scale by scale square coloured
+// by the class of instruction it holds. Cells which are only ever pushed in string mode are coloured as
+// strings, and unreachable cells are grey.
+func (cB *CodeBox) WriteThumbnail(w io.Writer, scale int) error {
+	if scale < 1 {
+		scale = 1
+	}
+	kinds := cB.CellKinds()
+	img := image.NewPaletted(image.Rect(0, 0, cB.width*scale, cB.height*scale), color.Palette{thumbWater,
+		thumbMove, thumbMath, thumbStack, thumbIO, thumbBox, thumbLiteral, thumbString, thumbHalt, thumbOther})
+	for y, line := range cB.box {
+		for x, r := range line {
+			c := uint8(img.Palette.Index(thumbColour(r, kinds[y][x])))
+			for py := y * scale; py < (y+1)*scale; py++ {
+				for px := x * scale; px < (x+1)*scale; px++ {
+					img.SetColorIndex(px, py, c)
+				}
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
@@ -0,0 +1,30 @@
+package fish
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestWriteThumbnail(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := NewCodeBox(`1"a"n;Z`, nil, Spec).WriteThumbnail(buf, 2); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 14 || b.Dy() != 2 {
+		t.Fatalf("got a %dx%d thumbnail, want 14x2", b.Dx(), b.Dy())
+	}
+	for i, want := range []color.Color{thumbLiteral, thumbString, thumbString, thumbString, thumbIO, thumbHalt,
+		thumbOther} {
+		r, g, b, _ := img.At(i*2+1, 1).RGBA()
+		wr, wg, wb, _ := want.RGBA()
+		if r != wr || g != wg || b != wb {
+			t.Errorf("cell %d has the wrong colour", i)
+		}
+	}
+}
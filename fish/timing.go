@@ -0,0 +1,41 @@
+package fish
+
+import (
+	"math"
+	"time"
+)
+
+// sleepUnit is the length of time "S" sleeps for per unit popped.
+const sleepUnit = 100 * time.Millisecond
+
+// Timing is an Extension which lets a ><> animate its output and tell the time, as in starfish. It
+// implements "S", which pops n and sleeps for n*100ms, and "h", "m" and "s", which push the current hour,
+// minute and second. They use CodeBox.Sleep and CodeBox.Now, so a deterministic ><> doesn't sleep, and
+// sees the clock stopped at midnight.
+type Timing struct{}
+
+// Instructions implements Extension.
+func (Timing) Instructions() map[byte]Instruction {
+	return map[byte]Instruction{'S': sleep, 'h': clockPart(time.Time.Hour), 'm': clockPart(time.Time.Minute),
+		's': clockPart(time.Time.Second)}
+}
+
+// Manifest implements Declarer.
+func (Timing) Manifest() Manifest {
+	return Manifest{Name: "Timing", Ops: "Shms", Needs: []Capability{Clock}}
+}
+
+func sleep(cB *CodeBox) {
+	n := cB.Pop()
+	if math.IsNaN(n) || n < 0 || n*float64(sleepUnit) > math.MaxInt64 {
+		panic(failf(ErrOutOfRange, "Sleep duration %v is out of range!", n))
+	}
+	cB.Sleep(time.Duration(n * float64(sleepUnit)))
+}
+
+// clockPart returns an instruction which pushes the part of the current time returned by part.
+func clockPart(part func(time.Time) int) Instruction {
+	return func(cB *CodeBox) {
+		cB.Push(float64(part(cB.Now())))
+	}
+}
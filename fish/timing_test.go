@@ -0,0 +1,57 @@
+package fish
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTiming(t *testing.T) {
+	out := new(bytes.Buffer)
+	cB := NewCodeBox("hn' 'omn' 'osn;", nil, Spec, WithExtension(Timing{}), WithOutput(out))
+	cB.now = func() time.Time {
+		return time.Date(2016, 12, 25, 13, 45, 7, 0, time.UTC)
+	}
+	if _, err := cB.Run(100); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "13 45 7" {
+		t.Errorf("got %q", out)
+	}
+
+	start := time.Now()
+	if _, err := NewCodeBox("1S;", nil, Spec, WithExtension(Timing{})).Run(10); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < sleepUnit {
+		t.Errorf("slept for %v, want %v", d, sleepUnit)
+	}
+	start = time.Now()
+	if _, err := NewCodeBox("a0*S;", nil, Spec, WithExtension(Timing{}), WithDeterministic()).Run(10); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d >= sleepUnit {
+		t.Errorf("slept for %v in a deterministic run", d)
+	}
+	if _, err := NewCodeBox("01-S;", nil, Spec, WithExtension(Timing{})).Run(10); err == nil {
+		t.Error("slept for a negative duration")
+	}
+}
+
+func TestTimingCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(sleepUnit)
+		cancel()
+	}()
+	start := time.Now()
+	// Sleeps for 100 seconds unless cancelled
+	cB := NewCodeBox("aa*a*S;", nil, Spec, WithExtension(Timing{}))
+	if _, err := cB.RunContext(ctx, 10); err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > 10*sleepUnit {
+		t.Errorf("cancelling took %v", d)
+	}
+}
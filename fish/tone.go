@@ -0,0 +1,88 @@
+package fish
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Tone is an Extension which lets a ><> compose music. It implements "T", which pops a duration in
+// milliseconds, of at most a minute, and then a frequency in Hz, and appends a tone to an in-memory buffer.
+// A frequency of 0 is a rest. The buffer can be retrieved as a WAV file with Tone.WAV, and holds at most ten
+// minutes of audio.
+type Tone struct {
+	SampleRate int
+	samples    []int16
+}
+
+// maxToneMs is the longest tone "T" appends, in milliseconds, so a ><> can't exhaust memory with one.
+const maxToneMs = 60000
+
+// maxWAVMs is the most audio a Tone buffers, in milliseconds, so a ><> can't exhaust memory with many tones.
+const maxWAVMs = 10 * maxToneMs
+
+// ErrAudioFull is the cause of a Failure when "T" would make a Tone's buffer longer than ten minutes.
+var ErrAudioFull = errors.New("audio buffer is full")
+
+// NewTone returns a pointer to a Tone which samples at sampleRate Hz.
+func NewTone(sampleRate int) *Tone {
+	return &Tone{SampleRate: sampleRate}
+}
+
+// Instructions implements Extension.
+func (t *Tone) Instructions() map[byte]Instruction {
+	return map[byte]Instruction{'T': t.tone}
+}
+
+// Manifest implements Declarer.
+func (t *Tone) Manifest() Manifest {
+	return Manifest{Name: "Tone", Ops: "T"}
+}
+
+func (t *Tone) tone(cB *CodeBox) {
+	ms := cB.Pop()
+	freq := cB.Pop()
+	if ms < 0 || freq < 0 {
+		panic(failf(ErrOutOfRange, "Tone cannot have a negative duration or frequency!"))
+	}
+	if math.IsNaN(ms) || ms > maxToneMs {
+		panic(failf(ErrOutOfRange, "Tone duration %v is out of range!", ms))
+	}
+	n := int(float64(t.SampleRate) * ms / 1000)
+	if float64(len(t.samples)+n)*1000/float64(t.SampleRate) > maxWAVMs {
+		panic(failf(ErrAudioFull, "Tone of %vms would make the audio longer than %d minutes!", ms,
+			maxWAVMs/maxToneMs))
+	}
+	for i := 0; i < n; i++ {
+		v := math.Sin(2 * math.Pi * freq * float64(i) / float64(t.SampleRate))
+		t.samples = append(t.samples, int16(v*math.MaxInt16/2))
+	}
+}
+
+// Duration returns the length of the buffered audio in milliseconds.
+func (t *Tone) Duration() float64 {
+	return float64(len(t.samples)) * 1000 / float64(t.SampleRate)
+}
+
+// Reset empties the buffer.
+func (t *Tone) Reset() {
+	t.samples = t.samples[:0]
+}
+
+// WAV returns the buffer encoded as a mono 16-bit PCM WAV file.
+func (t *Tone) WAV() []byte {
+	buf := new(bytes.Buffer)
+	size := uint32(len(t.samples) * 2)
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, 36+size)
+	buf.WriteString("WAVEfmt ")
+	binary.Write(buf, binary.LittleEndian, []uint32{16})
+	binary.Write(buf, binary.LittleEndian, []uint16{1, 1}) // PCM, mono
+	binary.Write(buf, binary.LittleEndian, []uint32{uint32(t.SampleRate), uint32(t.SampleRate * 2)})
+	binary.Write(buf, binary.LittleEndian, []uint16{2, 16})
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, size)
+	binary.Write(buf, binary.LittleEndian, t.samples)
+	return buf.Bytes()
+}
@@ -0,0 +1,52 @@
+package fish
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestTone(t *testing.T) {
+	tone := NewTone(8000)
+	cB := NewCodeBox("T0aT;", []float64{440, 10}, Spec, WithExtension(tone))
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if tone.Duration() != 20 || len(tone.samples) != 160 || tone.samples[100] != 0 {
+		t.FailNow()
+	}
+	wav := tone.WAV()
+	if len(wav) != 44+320 || !bytes.Equal(wav[:4], []byte("RIFF")) || !bytes.Equal(wav[8:16], []byte("WAVEfmt ")) {
+		t.FailNow()
+	}
+	tone.Reset()
+	if tone.Duration() != 0 {
+		t.Fail()
+	}
+
+	// A 10^12 ms tone would need terabytes of samples
+	cB = NewCodeBox("T;", []float64{440, 1e12}, Spec, WithExtension(tone))
+	if _, err := cB.Run(0); err == nil || err.Error() != "Tone duration 1e+12 is out of range! ('T' at 0,0)" {
+		t.Errorf("got %v", err)
+	}
+	if tone.Duration() != 0 {
+		t.Error("appended part of an out of range tone")
+	}
+
+	// Ten one minute tones fill the buffer, and the eleventh fails without appending anything
+	var stack []float64
+	for i := 0; i < 10; i++ {
+		stack = append(stack, 0, maxToneMs)
+	}
+	cB = NewCodeBox("TTTTTTTTTT;", stack, Spec, WithExtension(tone))
+	if _, err := cB.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	cB = NewCodeBox("01T;", nil, Spec, WithExtension(tone))
+	if _, err := cB.Run(0); !errors.Is(err, ErrAudioFull) {
+		t.Errorf("got %v, want ErrAudioFull", err)
+	}
+	if tone.Duration() != maxWAVMs {
+		t.Errorf("got %vms of audio, want %d", tone.Duration(), maxWAVMs)
+	}
+}
@@ -0,0 +1,306 @@
+package fish
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// A trace records the state of a ><> before every step it executes, compactly enough for runs of millions
+// of steps. The format is:
+//
+//	header:  "FISHTRC" 1, flags byte (1 if blocks are compressed), block size uvarint
+//	blocks:  one per block size steps, each optionally compressed with DEFLATE
+//	index:   the offset of each block, as little-endian uint64s
+//	trailer: the offset of the index, the number of steps and the number of blocks, as little-endian uint64s
+//
+// A block is a sequence of records: x, y and the number of stacks as uvarints, the direction as a byte (with
+// the top bit set in string mode), the instruction as a uvarint code point, then the current stack as a delta
+// from the previous record's: the number of values kept as a uvarint, the number of values added as a
+// uvarint, and the added values as little-endian float64 bits.
+// The first record of a block is relative to an empty stack, so blocks can be decoded independently.
+var traceMagic = []byte("FISHTRC\x02")
+
+const traceTrailer = 24
+
+// TraceStep is the state of a ><> before one step, as recorded in a trace.
+type TraceStep struct {
+	Step   int // The number of steps executed before this one
+	X, Y   int
+	Dir    Direction
+	Op     rune
+	String bool // Whether Op is being pushed in string mode, rather than executed
+	Stacks int
+	Stack  []float64 // The current stack
+}
+
+// TraceWriter writes a trace. Attach one to a CodeBox with WithTrace, and Close it after the run.
+type TraceWriter struct {
+	w         *bufio.Writer
+	offset    int64
+	blockSize int
+	compress  bool
+	block     bytes.Buffer
+	prev      []float64
+	n         int
+	index     []int64
+	err       error
+}
+
+// NewTraceWriter returns a TraceWriter which writes a trace to w in blocks of blockSize steps, compressing
+// each one if compress is set. Smaller blocks make seeking faster, larger ones make the trace smaller.
+func NewTraceWriter(w io.Writer, blockSize int, compress bool) *TraceWriter {
+	if blockSize < 1 {
+		blockSize = 4096
+	}
+	tw := &TraceWriter{w: bufio.NewWriter(w), blockSize: blockSize, compress: compress}
+	header := append([]byte{}, traceMagic...)
+	if compress {
+		header = append(header, 1)
+	} else {
+		header = append(header, 0)
+	}
+	tw.write(binary.AppendUvarint(header, uint64(blockSize)))
+	return tw
+}
+
+func (tw *TraceWriter) write(b []byte) {
+	if tw.err == nil {
+		_, tw.err = tw.w.Write(b)
+		tw.offset += int64(len(b))
+	}
+}
+
+// Record appends s to the trace. Steps must be recorded in order.
+func (tw *TraceWriter) Record(s TraceStep) {
+	if tw.n%tw.blockSize == 0 {
+		tw.flush()
+	}
+	b := binary.AppendUvarint(nil, uint64(s.X))
+	b = binary.AppendUvarint(b, uint64(s.Y))
+	b = binary.AppendUvarint(b, uint64(s.Stacks))
+	if s.String {
+		b = append(b, byte(s.Dir)|0x80)
+	} else {
+		b = append(b, byte(s.Dir))
+	}
+	b = binary.AppendUvarint(b, uint64(s.Op))
+	keep := 0
+	for keep < len(s.Stack) && keep < len(tw.prev) && math.Float64bits(s.Stack[keep]) == math.Float64bits(tw.prev[keep]) {
+		keep++
+	}
+	b = binary.AppendUvarint(b, uint64(keep))
+	b = binary.AppendUvarint(b, uint64(len(s.Stack)-keep))
+	for _, v := range s.Stack[keep:] {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	}
+	tw.block.Write(b)
+	tw.prev = append(tw.prev[:0], s.Stack...)
+	tw.n++
+}
+
+// flush writes the current block, if there is one, and starts a new one.
+func (tw *TraceWriter) flush() {
+	if tw.block.Len() > 0 {
+		tw.index = append(tw.index, tw.offset)
+		if tw.compress {
+			var buf bytes.Buffer
+			fw, _ := flate.NewWriter(&buf, flate.BestSpeed)
+			fw.Write(tw.block.Bytes())
+			fw.Close()
+			tw.write(buf.Bytes())
+		} else {
+			tw.write(tw.block.Bytes())
+		}
+	}
+	tw.block.Reset()
+	tw.prev = tw.prev[:0]
+}
+
+// Close writes the end of the trace, and returns the first error encountered writing it.
+func (tw *TraceWriter) Close() error {
+	tw.flush()
+	indexOffset := tw.offset
+	var b []byte
+	for _, o := range tw.index {
+		b = binary.LittleEndian.AppendUint64(b, uint64(o))
+	}
+	b = binary.LittleEndian.AppendUint64(b, uint64(indexOffset))
+	b = binary.LittleEndian.AppendUint64(b, uint64(tw.n))
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(tw.index)))
+	tw.write(b)
+	if tw.err == nil {
+		tw.err = tw.w.Flush()
+	}
+	return tw.err
+}
+
+// WithTrace records the ><>'s state in tw before each step.
+func WithTrace(tw *TraceWriter) Option {
+	return func(cB *CodeBox) {
+		cB.observers = append(cB.observers, func(x, y int) {
+			r := cB.cell(x, y)
+			tw.Record(TraceStep{cB.steps, x, y, cB.fDir, r, cB.stringMode != 0 && r != rune(cB.stringMode),
+				cB.p + 1, cB.Stack()})
+		})
+	}
+}
+
+// TraceReader reads steps from a trace in any order.
+type TraceReader struct {
+	r           io.ReaderAt
+	compressed  bool
+	blockSize   int
+	steps       int
+	index       []int64
+	indexOffset int64
+	cached      int // The block in decoded, or -1
+	decoded     []TraceStep
+}
+
+var errBadTrace = errors.New("trace: invalid trace")
+
+// OpenTrace returns a TraceReader for the trace of the given size in r.
+func OpenTrace(r io.ReaderAt, size int64) (*TraceReader, error) {
+	header := make([]byte, len(traceMagic)+1+binary.MaxVarintLen64)
+	if size < int64(len(traceMagic)+2+traceTrailer) {
+		return nil, errBadTrace
+	}
+	if n, err := r.ReadAt(header, 0); n < len(traceMagic)+2 && err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:len(traceMagic)], traceMagic) {
+		return nil, errBadTrace
+	}
+	tr := &TraceReader{r: r, compressed: header[len(traceMagic)] == 1, cached: -1}
+	bs, n := binary.Uvarint(header[len(traceMagic)+1:])
+	if n <= 0 || bs == 0 || bs > math.MaxInt32 {
+		return nil, errBadTrace
+	}
+	tr.blockSize = int(bs)
+
+	trailer := make([]byte, traceTrailer)
+	if _, err := r.ReadAt(trailer, size-traceTrailer); err != nil {
+		return nil, err
+	}
+	tr.indexOffset = int64(binary.LittleEndian.Uint64(trailer))
+	tr.steps = int(binary.LittleEndian.Uint64(trailer[8:]))
+	blocks := int64(binary.LittleEndian.Uint64(trailer[16:]))
+	// Checked in this order so nothing can overflow, and a trace can't claim more steps than its blocks hold
+	if tr.indexOffset < 0 || tr.indexOffset > size || blocks < 0 || blocks > (size-traceTrailer)/8 ||
+		tr.indexOffset+blocks*8 != size-traceTrailer || tr.steps < 0 || int64(tr.steps) > blocks*int64(tr.blockSize) {
+		return nil, errBadTrace
+	}
+	index := make([]byte, blocks*8)
+	if _, err := r.ReadAt(index, tr.indexOffset); err != nil {
+		return nil, err
+	}
+	for i := int64(0); i < blocks; i++ {
+		tr.index = append(tr.index, int64(binary.LittleEndian.Uint64(index[i*8:])))
+	}
+	return tr, nil
+}
+
+// Len returns the number of steps in the trace.
+func (tr *TraceReader) Len() int {
+	return tr.steps
+}
+
+// At returns the state before the step'th step, counting from 0.
+func (tr *TraceReader) At(step int) (TraceStep, error) {
+	if step < 0 || step >= tr.steps {
+		return TraceStep{}, fmt.Errorf("trace: step %d out of range", step)
+	}
+	if err := tr.load(step / tr.blockSize); err != nil {
+		return TraceStep{}, err
+	}
+	if step%tr.blockSize >= len(tr.decoded) {
+		return TraceStep{}, errBadTrace
+	}
+	return tr.decoded[step%tr.blockSize], nil
+}
+
+// load decodes block b, unless it is already decoded.
+func (tr *TraceReader) load(b int) error {
+	if b == tr.cached {
+		return nil
+	}
+	end := tr.indexOffset
+	if b+1 < len(tr.index) {
+		end = tr.index[b+1]
+	}
+	if b >= len(tr.index) || end < tr.index[b] {
+		return errBadTrace
+	}
+	var r io.Reader = io.NewSectionReader(tr.r, tr.index[b], end-tr.index[b])
+	if tr.compressed {
+		r = flate.NewReader(r)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	tr.cached = -1
+	tr.decoded = tr.decoded[:0]
+	var stack []float64
+	d := &traceDecoder{Reader: bytes.NewReader(data)}
+	for step := b * tr.blockSize; d.Len() > 0; step++ {
+		s := TraceStep{Step: step, X: d.uvarint(), Y: d.uvarint(), Stacks: d.uvarint()}
+		dir := d.byte()
+		s.Dir, s.Op, s.String = Direction(dir&^0x80), rune(d.uvarint()), dir&0x80 != 0
+		keep, added := d.uvarint(), d.uvarint()
+		if d.err != nil || keep > len(stack) || added > d.Len()/8 {
+			return errBadTrace
+		}
+		s.Stack = make([]float64, keep, keep+added)
+		copy(s.Stack, stack)
+		for i := 0; i < added; i++ {
+			s.Stack = append(s.Stack, d.float())
+		}
+		stack = s.Stack
+		tr.decoded = append(tr.decoded, s)
+	}
+	tr.cached = b
+	return nil
+}
+
+// traceDecoder reads the fields of trace records, remembering the first error.
+type traceDecoder struct {
+	*bytes.Reader
+	err error
+}
+
+func (d *traceDecoder) uvarint() int {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.Reader)
+	if err != nil || v > math.MaxInt32 {
+		d.err = errBadTrace
+	}
+	return int(v)
+}
+
+func (d *traceDecoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	b, err := d.ReadByte()
+	d.err = err
+	return b
+}
+
+func (d *traceDecoder) float() float64 {
+	var b [8]byte
+	if _, err := io.ReadFull(d.Reader, b[:]); err != nil {
+		d.err = err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b[:]))
+}
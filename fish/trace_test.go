@@ -0,0 +1,104 @@
+package fish
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func writeTrace(t *testing.T, script string, blockSize int, compress bool) *TraceReader {
+	buf := new(bytes.Buffer)
+	tw := NewTraceWriter(buf, blockSize, compress)
+	cB := NewCodeBox(script, nil, Spec, WithTrace(tw))
+	for !cB.swim() {
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := OpenTrace(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+func TestTrace(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		tr := writeTrace(t, "12v\n;~<", 2, compress)
+		if tr.Len() != 6 {
+			t.Fatalf("got %d steps, want 6", tr.Len())
+		}
+		// Read out of order, to seek between blocks.
+		for _, i := range []int{5, 0, 3, 4, 1, 2} {
+			s, err := tr.At(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []TraceStep{
+				{0, 0, 0, Right, '1', false, 1, []float64{}},
+				{1, 1, 0, Right, '2', false, 1, []float64{1}},
+				{2, 2, 0, Right, 'v', false, 1, []float64{1, 2}},
+				{3, 2, 1, Down, '<', false, 1, []float64{1, 2}},
+				{4, 1, 1, Left, '~', false, 1, []float64{1, 2}},
+				{5, 0, 1, Left, ';', false, 1, []float64{1}},
+			}[i]
+			if s.Step != want.Step || s.X != want.X || s.Y != want.Y || s.Dir != want.Dir || s.Op != want.Op ||
+				s.Stacks != want.Stacks || !sameValues(s.Stack, want.Stack) {
+				t.Errorf("compress=%v, step %d: got %+v, want %+v", compress, i, s, want)
+			}
+		}
+		if _, err := tr.At(6); err == nil {
+			t.Error("expected an error reading past the end")
+		}
+	}
+
+	if _, err := OpenTrace(bytes.NewReader([]byte("not a trace at all, really not")), 30); err == nil {
+		t.Error("expected an error opening garbage")
+	}
+}
+
+func TestTraceCorrupt(t *testing.T) {
+	buf := new(bytes.Buffer)
+	tw := NewTraceWriter(buf, 2, false)
+	cB := NewCodeBox("12v\n;~<", nil, Spec, WithTrace(tw))
+	for !cB.swim() {
+	}
+	tw.Close()
+	trailer := len(buf.Bytes()) - traceTrailer
+
+	// A trace claiming more steps than its blocks hold
+	b := append([]byte(nil), buf.Bytes()...)
+	binary.LittleEndian.PutUint64(b[trailer+8:], 7)
+	if _, err := OpenTrace(bytes.NewReader(b), int64(len(b))); err != errBadTrace {
+		t.Errorf("got %v for too many steps", err)
+	}
+	// A trace with more blocks than fit in it, whose index size would overflow
+	b = append([]byte(nil), buf.Bytes()...)
+	binary.LittleEndian.PutUint64(b[trailer+16:], 1<<61+1)
+	if _, err := OpenTrace(bytes.NewReader(b), int64(len(b))); err != errBadTrace {
+		t.Errorf("got %v for too many blocks", err)
+	}
+
+	// A block holding fewer steps than the trace claims
+	tr, err := OpenTrace(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cut the last block after its first record, of step 4: 7 bytes then the 2 values pushed
+	tr.indexOffset = tr.index[2] + 7 + 16
+	if _, err := tr.At(5); err != errBadTrace {
+		t.Errorf("got %v for a short block", err)
+	}
+}
+
+func TestTraceSize(t *testing.T) {
+	buf := new(bytes.Buffer)
+	tw := NewTraceWriter(buf, 0, true)
+	cB := NewCodeBox("aa*>:1-:0=?;30.", nil, Spec, WithTrace(tw))
+	for !cB.swim() {
+	}
+	tw.Close()
+	if buf.Len() > 4*cB.steps {
+		t.Errorf("trace of %d steps is %d bytes", cB.steps, buf.Len())
+	}
+}
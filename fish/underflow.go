@@ -0,0 +1,59 @@
+package fish
+
+// UnderflowFunc is called when the instruction op needs more values than the current stack holds, or pops
+// the stack when it is empty. It returns the value to use in place of each missing one, or false to let the
+// instruction fail as usual.
+type UnderflowFunc func(op byte) (float64, bool)
+
+// OnUnderflow registers f to handle stack underflows, which allows the lenient behaviour of some online
+// interpreters, such as treating an empty stack as holding zeros.
+func OnUnderflow(f UnderflowFunc) Option {
+	return func(cB *CodeBox) {
+		cB.underflow = f
+	}
+}
+
+// maxFill is the most values an underflow handler is asked for at once, so a huge count given to "[" fails
+// as usual rather than filling the stack with it.
+const maxFill = 1 << 16
+
+// needs returns the number of values the instruction r needs on the current stack.
+func (cB *CodeBox) needs(r byte) int {
+	s := cB.stacks[cB.p]
+	switch r {
+	case '&':
+		if s.filledRegister {
+			return 0
+		}
+		return 1
+	case '{', '}':
+		return 1
+	case '[':
+		if len(s.S) > 0 && s.S[len(s.S)-1] > 0 {
+			return toInt(s.S[len(s.S)-1], "Stack size") + 1
+		}
+	}
+	return pops[r]
+}
+
+// fill calls the underflow handler for each value r needs which is missing from the current stack, and
+// places the values it returns at the bottom of the stack, untainted.
+func (cB *CodeBox) fill(r byte) {
+	s := cB.stacks[cB.p]
+	missing := cB.needs(r) - len(s.S)
+	if missing <= 0 || missing > maxFill {
+		return
+	}
+	values := make([]float64, 0, missing+len(s.S))
+	for i := 0; i < missing; i++ {
+		v, ok := cB.underflow(r)
+		if !ok {
+			return
+		}
+		values = append(values, v)
+	}
+	s.S = append(values, s.S...)
+	if s.taint != nil {
+		s.taint = append(make([]bool, missing, missing+len(s.taint)), s.taint...)
+	}
+}
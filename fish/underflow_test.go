@@ -0,0 +1,64 @@
+package fish
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestOnUnderflow(t *testing.T) {
+	zero := OnUnderflow(func(op byte) (float64, bool) {
+		return 0, true
+	})
+	tests := []struct {
+		script string
+		want   []float64
+	}{
+		{"5+;", []float64{5}},
+		{"5-;", []float64{-5}},
+		{":;", []float64{0, 0}},
+		{"1@;", []float64{1, 0, 0}},
+		{"&&;", []float64{0}},
+		{"3[l;", []float64{0, 0, 0, 3}},
+	}
+	for _, test := range tests {
+		cB := NewCodeBox(test.script, []float64{}, Spec, zero)
+		if fail := swimAll(cB); fail != "" {
+			t.Errorf("%q failed: %s", test.script, fail)
+			continue
+		}
+		s := cB.Stack()
+		if len(s) != len(test.want) {
+			t.Errorf("%q: got %v, want %v", test.script, s, test.want)
+			continue
+		}
+		for i := range s {
+			if s[i] != test.want[i] {
+				t.Errorf("%q: got %v, want %v", test.script, s, test.want)
+			}
+		}
+	}
+
+	abort := OnUnderflow(func(op byte) (float64, bool) {
+		return 0, op != '+'
+	})
+	if swimAll(NewCodeBox("+;", []float64{}, Spec, abort)) != "Stack is empty!" {
+		t.Fail()
+	}
+	// A count far larger than the stack isn't filled
+	if swimAll(NewCodeBox("aaaaaaaaa*******[;", []float64{}, Spec, zero)) != "Stack is empty!" {
+		t.Error("filled a stack of 10^9 values")
+	}
+	// Filled values are untainted, and don't shift the taint of the others
+	var taint Taint
+	cB := NewCodeBox("i3[n;", []float64{}, Spec, zero, WithTaint(&taint), WithInput(strings.NewReader("a")),
+		WithOutput(ioutil.Discard))
+	if fail := swimAll(cB); fail != "" || len(taint.Outputs) != 1 || !taint.Outputs[0] {
+		t.Errorf("got %q, %v", fail, taint.Outputs)
+	}
+
+	tone := NewTone(100)
+	if swimAll(NewCodeBox("T;", []float64{}, Spec, WithExtension(tone), abort)) != "" {
+		t.Fail()
+	}
+}
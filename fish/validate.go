@@ -0,0 +1,51 @@
+package fish
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Warning is a likely mistake found by Validate.
+type Warning struct {
+	X, Y int
+	Msg  string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("(%d,%d): %s", w.X, w.Y, w.Msg)
+}
+
+// Validate checks every path the ><> may swim for likely mistakes, and returns a Warning for each one it
+// finds, ordered by position.
+func (cB *CodeBox) Validate() (warnings []Warning) {
+	cB.explore(func(s state, r byte) {
+		if s.stringMode == 0 && (r == '"' || r == '\'') && cB.unclosed(s.x, s.y, s.dir) {
+			warnings = append(warnings, Warning{s.x, s.y, fmt.Sprintf(
+				"string opened while moving %v is never closed; it wraps around to its own %q", s.dir, r)})
+		}
+	})
+	sort.Slice(warnings, func(i, j int) bool {
+		if warnings[i].Y != warnings[j].Y {
+			return warnings[i].Y < warnings[j].Y
+		}
+		return warnings[i].X < warnings[j].X
+	})
+	return
+}
+
+// unclosed returns true if the quote at (x,y) is the first matching quote found when swimming from it in
+// direction d, meaning the string it opens covers its whole row or column.
+func (cB *CodeBox) unclosed(x, y int, d Direction) bool {
+	q := cB.box[y][x]
+	nx, ny := cB.next(x, y, d, 1)
+	for cB.box[ny][nx] != q {
+		nx, ny = cB.next(nx, ny, d, 1)
+	}
+	return nx == x && ny == y
+}
+
+// InStringMode returns true if the ><> is currently in string mode. A host which stops a ><> before it
+// halts can use it to report that a string was never closed.
+func (cB *CodeBox) InStringMode() bool {
+	return cB.stringMode != 0
+}
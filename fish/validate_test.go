@@ -0,0 +1,32 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	cB := NewCodeBox("\"Hello\"ooooo;", []float64{}, Spec)
+	if w := cB.Validate(); len(w) != 0 {
+		t.Fatal(w)
+	}
+
+	cB = NewCodeBox("v\n>\"Hello, world!r>o<\n\n 'ignored", []float64{}, Spec)
+	w := cB.Validate()
+	if len(w) != 1 || w[0].X != 1 || w[0].Y != 1 {
+		t.Fatal(w)
+	}
+}
+
+func TestInStringMode(t *testing.T) {
+	cB := NewCodeBox(`"abc"`, []float64{}, Spec)
+	cB.Swim()
+	if !cB.InStringMode() {
+		t.FailNow()
+	}
+	for i := 0; i < 4; i++ {
+		cB.Swim()
+	}
+	if cB.InStringMode() {
+		t.Fail()
+	}
+}
@@ -0,0 +1,62 @@
+package fish
+
+import (
+	"sort"
+)
+
+// version is the version of the package, following semantic versioning. It's bumped whenever the
+// behaviour of a Mode or the set of Features changes.
+const version = "1.0.0"
+
+// Version returns the version of the package, such as "1.0.0", so tools built on it can report it.
+func Version() string {
+	return version
+}
+
+// Feature describes something the package supports, as listed by Features.
+type Feature struct {
+	Kind string // "dialect", "extension" or "backend"
+	Name string // The name of the Mode for a dialect, or of the type or function providing the feature
+	Ops  string // The instructions an extension provides, in order
+}
+
+// Features lists the dialects, extensions and execution backends the package supports, so tools layered on
+// it can negotiate capabilities and show them to users. Dialects are listed in Mode order, followed by the
+// extensions and then the backends, each in alphabetical order.
+func Features() []Feature {
+	var fs []Feature
+	for m := range modeNames {
+		fs = append(fs, Feature{Kind: "dialect", Name: Mode(m).String()})
+	}
+	for _, d := range []Declarer{AssertExtension{}, CallExtension{}, DebugExtension{}, NumberInput{},
+		&StoreExtension{}, Timing{}, &Tone{}} {
+		m := d.Manifest()
+		fs = append(fs, Feature{Kind: "extension", Name: m.Name, Ops: m.Ops})
+	}
+	// NewCodeBox parses the script itself, while Compile parses it once for many CodeBoxes.
+	fs = append(fs, Feature{Kind: "backend", Name: "Compile"}, Feature{Kind: "backend", Name: "NewCodeBox"})
+	return fs
+}
+
+// extensionOps returns the instructions ext provides, in order.
+func extensionOps(ext Extension) string {
+	var ops []byte
+	for op := range ext.Instructions() {
+		ops = append(ops, op)
+	}
+	return sortOps(string(ops))
+}
+
+// sortOps returns the instructions in ops in order, without repeats.
+func sortOps(ops string) string {
+	b := []byte(ops)
+	sort.Slice(b, func(i, j int) bool { return b[i] < b[j] })
+	n := 0
+	for i, op := range b {
+		if i == 0 || op != b[n-1] {
+			b[n] = op
+			n++
+		}
+	}
+	return string(b[:n])
+}
@@ -0,0 +1,34 @@
+package fish
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	if !regexp.MustCompile(`^\d+\.\d+\.\d+$`).MatchString(Version()) {
+		t.Errorf("%q isn't a semantic version", Version())
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	kinds := make(map[string]int)
+	ops := make(map[string]string)
+	for _, f := range Features() {
+		kinds[f.Kind]++
+		ops[f.Name] = f.Ops
+	}
+	if kinds["dialect"] != len(modeNames) || kinds["extension"] != 7 || kinds["backend"] != 2 {
+		t.Errorf("got %v", kinds)
+	}
+	if ops["StoreExtension"] != "GP" || ops["AssertExtension"] != "A" {
+		t.Errorf("got %v", ops)
+	}
+	for _, f := range Features() {
+		if f.Kind == "dialect" {
+			if _, err := ParseMode(f.Name); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
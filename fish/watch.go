@@ -0,0 +1,83 @@
+package fish
+
+// Probe measures some aspect of a ><>'s state, for sampling by a Watch.
+type Probe func(cB *CodeBox) float64
+
+// StackDepth is a Probe of the length of the current stack.
+func StackDepth(cB *CodeBox) float64 {
+	return cB.StackLength()
+}
+
+// RegisterFilled is a Probe which is 1 if the current stack's register is filled, and 0 otherwise.
+func RegisterFilled(cB *CodeBox) float64 {
+	if cB.stacks[cB.p].filledRegister {
+		return 1
+	}
+	return 0
+}
+
+// OutputCount is a Probe of the number of "o" and "n" instructions executed so far.
+func OutputCount(cB *CodeBox) float64 {
+	return float64(cB.outputs)
+}
+
+// Cell returns a Probe of the value of the codebox at (x,y), as "g" would see it.
+func Cell(x, y int) Probe {
+	return func(cB *CodeBox) float64 {
+		return float64(cB.get(x, y))
+	}
+}
+
+// Series is a time series sampled by a Watch.
+type Series struct {
+	Name   string
+	Steps  []int // The number of steps executed before each sample
+	Values []float64
+	probe  Probe
+}
+
+// Watch samples a set of Probes every Every steps, starting before the first step. Attach one to a
+// CodeBox with WithWatch.
+type Watch struct {
+	Every  int
+	Series []*Series
+}
+
+// NewWatch returns a pointer to a Watch which samples every n steps.
+func NewWatch(n int) *Watch {
+	if n < 1 {
+		n = 1
+	}
+	return &Watch{Every: n}
+}
+
+// Add starts sampling p as the Series name, and returns it.
+func (w *Watch) Add(name string, p Probe) *Series {
+	s := &Series{Name: name, probe: p}
+	w.Series = append(w.Series, s)
+	return s
+}
+
+// AddExpr starts sampling the watch expression expr, as parsed by ParseExpr, as a Series named after it.
+func (w *Watch) AddExpr(expr string) (*Series, error) {
+	p, err := ParseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	return w.Add(expr, p), nil
+}
+
+// WithWatch samples the ><>'s state into w as it swims.
+func WithWatch(w *Watch) Option {
+	return func(cB *CodeBox) {
+		cB.observers = append(cB.observers, func(x, y int) {
+			if cB.steps%w.Every != 0 {
+				return
+			}
+			for _, s := range w.Series {
+				s.Steps = append(s.Steps, cB.steps)
+				s.Values = append(s.Values, s.probe(cB))
+			}
+		})
+	}
+}
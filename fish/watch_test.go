@@ -0,0 +1,35 @@
+package fish
+
+import (
+	"testing"
+)
+
+func TestWatch(t *testing.T) {
+	w := NewWatch(2)
+	depth := w.Add("depth", StackDepth)
+	reg := w.Add("register", RegisterFilled)
+	cell := w.Add("cell", Cell(6, 0))
+	cB := NewCodeBox("123&a60p~;", nil, Spec, WithWatch(w))
+	for !cB.swim() {
+	}
+
+	for _, test := range []struct {
+		s    *Series
+		want []float64
+	}{
+		{depth, []float64{0, 2, 2, 4, 2}},
+		{reg, []float64{0, 0, 1, 1, 1}},
+		{cell, []float64{'0', '0', '0', '0', 10}},
+	} {
+		if len(test.s.Values) != len(test.want) {
+			t.Errorf("%s: got %v, want %v", test.s.Name, test.s.Values, test.want)
+			continue
+		}
+		for i := range test.want {
+			if test.s.Values[i] != test.want[i] || test.s.Steps[i] != i*2 {
+				t.Errorf("%s: got %v at %v, want %v", test.s.Name, test.s.Values, test.s.Steps, test.want)
+				break
+			}
+		}
+	}
+}
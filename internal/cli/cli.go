@@ -0,0 +1,14 @@
+// Package cli holds what the go-fish and gofish commands share, so that they behave alike: their exit
+// codes, and the configuration file and environment variables which fill in flags.
+package cli
+
+// Exit codes, so shell scripts can tell why a fish stopped.
+const (
+	ExitOK        = 0 // The fish halted normally
+	ExitError     = 1 // Something smelled fishy, e.g. the fish popped from an empty stack
+	ExitUsage     = 2 // The arguments were invalid; this is the code used by the flag package
+	ExitInvalid   = 3 // The script couldn't be loaded, or was empty
+	ExitStepLimit = 4 // The fish was stopped by -steps
+	ExitTimeout   = 5 // The fish was stopped by -timeout
+	ExitChanged   = 6 // The fish behaved differently to the trace -compare compared it with
+)
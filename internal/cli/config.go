@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ConfigName is the configuration file read from the working directory.
+const ConfigName = "gofish.toml"
+
+// EnvPrefix begins the name of each environment variable which sets a flag, e.g. GOFISH_STEPS.
+const EnvPrefix = "GOFISH_"
+
+// EnvName returns the name of the environment variable which sets the flag name.
+func EnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// Setting is a value read from a configuration file.
+type Setting struct {
+	Name, Value string
+	Line        int
+}
+
+// ReadConfig reads the settings in the configuration file at path, in order. It understands the subset of
+// TOML needed for flags: comments, section headers, and keys set to strings, numbers or booleans.
+func ReadConfig(path string) ([]Setting, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var settings []Setting
+	sc := bufio.NewScanner(file)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || text[0] == '#' || text[0] == '[' && strings.HasSuffix(text, "]") {
+			continue
+		}
+		eq := strings.Index(text, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("%s:%d: expected name = value", path, line)
+		}
+		name := strings.TrimSpace(text[:eq])
+		value, err := configValue(strings.TrimSpace(text[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %v", path, line, name, err)
+		}
+		settings = append(settings, Setting{name, value, line})
+	}
+	return settings, sc.Err()
+}
+
+// configValue returns the flag value written as the TOML value v, which may be followed by a comment.
+func configValue(v string) (string, error) {
+	if v == "" {
+		return "", fmt.Errorf("missing value")
+	}
+	switch v[0] {
+	case '"':
+		// A basic string, whose escapes are close enough to Go's
+		for i := 1; i < len(v); i++ {
+			if v[i] == '\\' {
+				i++
+			} else if v[i] == '"' {
+				if err := trailing(v[i+1:]); err != nil {
+					return "", err
+				}
+				return strconv.Unquote(v[:i+1])
+			}
+		}
+		return "", fmt.Errorf("unterminated string")
+	case '\'':
+		// A literal string, which has no escapes
+		end := strings.Index(v[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return v[1 : end+1], trailing(v[end+2:])
+	}
+	if i := strings.Index(v, "#"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	return strings.Replace(v, "_", "", -1), nil
+}
+
+// trailing checks that only a comment follows a value.
+func trailing(s string) error {
+	if s = strings.TrimSpace(s); s != "" && s[0] != '#' {
+		return fmt.Errorf("unexpected %q after value", s)
+	}
+	return nil
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/redstarcoder/go-fish/fish"
+	"github.com/redstarcoder/go-fish/internal/cli"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
 	"time"
 )
 
@@ -14,66 +19,348 @@ var (
 	flagscript = flag.String("code", "", "execute the script supplied in 'code'")
 	showstack = flag.Bool("s", false, "output the stack each tick")
 	help *bool = flag.Bool("h", false, "display this help message")
+	configfile = flag.String("config", "", "read settings from 'config' instead of "+cli.ConfigName)
+	version = flag.Bool("version", false, "display the version and the supported dialects, extensions and backends")
 	delay = flag.Duration("t", 0, "time to sleep between ticks (ex: 100ms)")
-	compmode = flag.Bool("m", false, "run like the fishlanguage.com interpreter")
+	runes = flag.Bool("runes", false, "lay the codebox out by UTF-8 character instead of by byte, so 'o' can output any character")
+	modename = flag.String("mode", "spec", "follow the behaviour of 'mode' where interpreters differ: spec, fishlanguage.com or fishinterpreter.com")
+	compmode = flag.Bool("m", false, "shorthand for -mode fishlanguage.com")
+	debugop = flag.Bool("debug", false, "enable the debugging instruction 'D', which prints the stack to stderr")
+	asserts = flag.Bool("assert", false, "enable the assertion instruction 'A', which fails if it pops 0")
+	calls = flag.Bool("calls", false, "enable the subroutine instructions 'C', which calls (x,y), and 'R', which returns, as in starfish")
+	timing = flag.Bool("timing", false, "enable the timing instructions 'S', which sleeps for n*100ms, and 'h', 'm' and 's', which push the time, as in starfish")
+	numinput = flag.Bool("numbers", false, "enable the instruction 'I', which reads a whole decimal number from the input")
+	jsonresult = flag.Bool("json", false, "run silently, then output the result as JSON")
+	inputfile = flag.String("input", "", "give the fish the contents of 'input' as its input")
+	maxsteps = flag.Int("steps", 0, "stop the fish after 'steps' ticks (0 is unlimited)")
+	timeout = flag.Duration("timeout", 0, "stop the fish once it has been swimming for 'timeout' (0 is unlimited)")
+	seed = flag.Int64("seed", 0, "seed the random directions chosen by 'x' with 'seed' (0 seeds them with the time)")
+	parallel = flag.Int("parallel", 1, "run up to 'parallel' scripts at once when running a directory")
+	plot = flag.Int("plot", 0, "sample the stack depth and output rate every 'plot' ticks, and plot them when the fish halts")
+	regionfile = flag.String("r", "", "label the regions listed in 'r' when outputting the codebox")
+	recorddir = flag.String("record", "", "when running a directory, record a trace and the output of each script into the directory 'record'")
+	comparedir = flag.String("compare", "", "when running a directory, compare each script with the trace and output recorded into the directory 'compare' by -record, reporting any changes")
+	thumbnail = flag.String("thumbnail", "", "write a PNG thumbnail of the codebox to 'thumbnail', coloured by instruction class, instead of running it")
+	soak = flag.Int("soak", 0, "run the script, or each script in a directory, 'soak' times over without output, failing if memory or goroutines accumulate")
+	report = flag.Bool("report", false, "output a Markdown report on the script's structure and a run of it, instead of running it")
+	challengefile = flag.String("challenge", "", "check the script, or rank a directory of scripts, against the JSON challenge in 'challenge' instead of running it")
 	initialstack = &stack{[]float64{}}
+	plugins pluginList
+	allowed capabilities
+	extensions []fish.Extension // Loaded from -plugin
+	mode fish.Mode
 	fName = "fish"
 )
 
 func Error() {
-	fmt.Println("Usage:", fName, "[args] <file>")
+	fmt.Println("Usage:", fName, "[args] <file or directory>")
 	flag.PrintDefaults()
+	fmt.Println()
+	fmt.Println("Any flag not given may be set by the environment variable " + cli.EnvPrefix + "<FLAG>, such as")
+	fmt.Println(cli.EnvPrefix + "STEPS, or else by a 'name = value' line in " + cli.ConfigName + ", such as 'steps = 1000'.")
+	fmt.Println(cli.ConfigName+" may only set", strings.Join(configurableNames(), ", ")+".")
+	fmt.Println("-plugin and -allow are only taken from the command line.")
+	fmt.Println()
+	fmt.Println("Exit codes:")
+	fmt.Println("  0	the fish halted normally")
+	fmt.Println("  1	something smelled fishy, or the script failed the challenge")
+	fmt.Println("  2	the arguments were invalid, or named a file which couldn't be read")
+	fmt.Println("  3	the script couldn't be loaded, or was empty")
+	fmt.Println("  4	the fish was stopped by -steps")
+	fmt.Println("  5	the fish was stopped by -timeout")
+	fmt.Println("  6	the fish behaved differently to the run it was compared with by -compare")
 }
 
-func loadScript(fName string) string {
+// thumbnailScale is the size of each cell in -thumbnail's output, in pixels.
+const thumbnailScale = 4
+
+// reportSteps limits the run described by -report, unless -steps is set.
+const reportSteps = 100000
+
+// soakSteps limits each run made by -soak, unless -steps is set.
+const soakSteps = 100000
+
+// parseMode returns the Mode chosen by -mode or -m, exiting if it's unknown.
+func parseMode() fish.Mode {
+	if *compmode {
+		return fish.FishLanguageCom
+	}
+	m, err := fish.ParseMode(*modename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(cli.ExitUsage)
+	}
+	return m
+}
+
+// printVersion outputs the version of the fish package and its features.
+func printVersion() {
+	fmt.Println("go-fish", fish.Version())
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	for _, f := range fish.Features() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", f.Kind, f.Name, f.Ops)
+	}
+	w.Flush()
+}
+
+// stop reports why the fish stopped, and exits with the matching code.
+func stop(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(exitCode(err))
+}
+
+func loadScript(fName string) (string, *fish.SourceMap) {
+	pp, err := fish.Preprocess(fName)
+	if err != nil {
+		stop(fmt.Errorf("%w: %v", errInvalidScript, err))
+	}
+	if pp.Script == "" {
+		stop(fmt.Errorf("%w: %s is empty", errInvalidScript, fName))
+	}
+	return pp.Script, pp.Map
+}
+
+func loadRegions(fName string) []fish.Region {
+	file, err := os.Open(fName)
+	if err != nil {
+		stop(fmt.Errorf("%w: %v", errInvalidArg, err))
+	}
+	defer file.Close()
+	regions, err := fish.ReadRegions(file)
+	if err != nil {
+		stop(fmt.Errorf("%w: %s: %v", errInvalidArg, fName, err))
+	}
+	return regions
+}
+
+func loadChallenge(fName string) *fish.Challenge {
 	file, err := os.Open(fName)
 	if err != nil {
-		panic(err)
+		stop(fmt.Errorf("%w: %v", errInvalidArg, err))
 	}
 	defer file.Close()
-	b, err := ioutil.ReadAll(file)
+	c, err := fish.ReadChallenge(file)
+	if err != nil {
+		stop(fmt.Errorf("%w: %s: %v", errInvalidArg, fName, err))
+	}
+	return c
+}
+
+// rank checks every .fish file under dir against c, and prints them best first. Files which can't be loaded
+// are listed as failing rather than stopping the ranking.
+func rank(c *fish.Challenge, dir string) {
+	solutions := make(map[string]string)
+	var broken []fish.Entry // Solutions which couldn't be loaded, ranked last
+	for _, path := range scriptPaths(dir) {
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			name = path
+		}
+		pp, err := fish.Preprocess(path)
+		if err != nil {
+			broken = append(broken, fish.Entry{Name: name, Err: err})
+			continue
+		}
+		solutions[name] = pp.Script
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(w, "Rank\tName\tBytes\tSteps\tArea")
+	for i, e := range append(fish.Rank(c, solutions), broken...) {
+		if e.Err != nil {
+			fmt.Fprintf(w, "-\t%s\tFailed: %v\n", e.Name, e.Err)
+		} else {
+			fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\n", i+1, e.Name, e.Bytes, e.Steps, e.Area)
+		}
+	}
+	w.Flush()
+}
+
+type jsonError struct {
+	Message string `json:"message"`
+	X       int    `json:"x"`
+	Y       int    `json:"y"`
+	Op      string `json:"op"`
+}
+
+type jsonResult struct {
+	Output   []byte     `json:"output"`
+	Stack    []float64  `json:"stack"`
+	Steps    int        `json:"steps"`
+	Duration int64      `json:"durationNs"`
+	Error    *jsonError `json:"error"`
+}
+
+// runJSON runs the fish silently, writes a jsonResult to stdout, and returns the error it stopped with.
+func runJSON(script string, opts []fish.Option) error {
+	res := execute(script, opts)
+	out := jsonResult{res.Output, res.Stack, res.Steps, int64(res.Duration), nil}
+	if f, ok := res.Err.(*fish.Failure); ok {
+		out.Error = &jsonError{f.Msg, f.X, f.Y, string(rune(f.Op))}
+	} else if res.Err != nil {
+		out.Error = &jsonError{Message: res.Err.Error()}
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+		stop(err)
+	}
+	return res.Err
+}
+
+func loadInput(fName string) []byte {
+	input, err := ioutil.ReadFile(fName)
 	if err != nil {
-		panic(err)
+		stop(fmt.Errorf("%w: %v", errInvalidArg, err))
 	}
-	return string(b)
+	return input
+}
+
+// printPlot prints the series collected by w, if -plot was given. It must be called before exiting, as
+// deferred calls don't run after os.Exit.
+func printPlot(w *fish.Watch) {
+	if w == nil {
+		return
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Stack depth:", w.Series[0].Sparkline())
+	fmt.Fprintln(os.Stderr, "Output rate:", w.Series[1].Deltas().Sparkline())
 }
 
 func init() {
 	fName = os.Args[0]
 	flag.Var(initialstack, "i", "set the initial stack (ex: '\"Example\" 10 \"stack\"')")
+	flag.Var(&allowed, "allow", "let plugins use the comma separated capabilities in 'allow': filesystem, network or clock")
+	flag.Var(&plugins, "plugin", "load extension instructions from 'plugin', a Go plugin ending in .so or a command speaking the fish.Process protocol; may be repeated")
 }
 
 func main() {
 	flag.Parse()
+	if err := configure(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(cli.ExitUsage)
+	}
+	mode = parseMode()
+	loadPlugins()
 	args := flag.Args()
+	if *version {
+		printVersion()
+		return
+	}
 	if *help || (*flagscript == "" && len(args) == 0) {
 		Error()
 		return
 	}
+	if *flagscript == "" {
+		if fi, err := os.Stat(args[0]); err == nil && fi.IsDir() {
+			if *challengefile != "" {
+				rank(loadChallenge(*challengefile), args[0])
+			} else if *soak > 0 {
+				var scripts []string
+				for _, path := range scriptPaths(args[0]) {
+					script, _ := loadScript(path)
+					scripts = append(scripts, script)
+				}
+				soakTest(scripts)
+			} else if code := batch(args[0]); code != cli.ExitOK {
+				os.Exit(code)
+			}
+			return
+		}
+	}
 	var script string
+	opts := options()
 	if script = *flagscript; script == "" {
-		script = loadScript(args[0])
+		var m *fish.SourceMap
+		script, m = loadScript(args[0])
+		opts = append(opts, fish.WithSourceMap(m))
 	}
-
-	cB := fish.NewCodeBox(script, initialstack.s, *compmode)
-	if !*showcodebox && !*showstack && *delay == 0 {
-		for !cB.Swim() {}
+	if *challengefile != "" {
+		steps, err := loadChallenge(*challengefile).Check(script)
+		if err != nil {
+			fmt.Println("Failed:", err)
+			os.Exit(cli.ExitError)
+		}
+		fmt.Println("Passed in", steps, "steps")
+		return
+	}
+	if *soak > 0 {
+		soakTest([]string{script})
+		return
+	}
+	if *thumbnail != "" {
+		file, err := os.Create(*thumbnail)
+		if err == nil {
+			err = fish.NewCodeBox(script, nil, mode, opts...).WriteThumbnail(file, thumbnailScale)
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			stop(err)
+		}
 		return
 	}
-	if *showcodebox {
-		cB.PrintBox()
+	if *report {
+		var input []byte
+		if *inputfile != "" {
+			input = loadInput(*inputfile)
+		}
+		steps := *maxsteps
+		if steps <= 0 {
+			steps = reportSteps
+		}
+		r, err := fish.Report(script, input, steps)
+		if err != nil {
+			stop(fmt.Errorf("%w: %v", errInvalidScript, err))
+		}
+		fmt.Print(r)
+		return
 	}
-	if *showstack && cB.StackLength() > 0 {
-		fmt.Println("Stack:", cB.Stack())
+	if *regionfile != "" {
+		opts = append(opts, fish.WithRegions(loadRegions(*regionfile)...))
 	}
-	time.Sleep(*delay)
-	for !cB.Swim() {
+
+	var watch *fish.Watch
+	if *plot > 0 {
+		watch = fish.NewWatch(*plot)
+		watch.Add("Stack depth", fish.StackDepth)
+		watch.Add("Output rate", fish.OutputCount)
+		opts = append(opts, fish.WithWatch(watch))
+	}
+
+	if *jsonresult {
+		err := runJSON(script, opts)
+		printPlot(watch)
+		if err != nil {
+			os.Exit(exitCode(err))
+		}
+		return
+	}
+	cB := fish.NewCodeBox(script, initialstack.s, mode, opts...)
+	if err := cB.Err(); err != nil {
+		stop(fmt.Errorf("%w: %v", errInvalidScript, err))
+	}
+	start := time.Now()
+	for steps := 0; ; steps++ {
 		if *showcodebox {
 			cB.PrintBox()
 		}
 		if *showstack && cB.StackLength() > 0 {
-			fmt.Println("Stack:", cB.Stack())
+			fmt.Fprintln(os.Stderr, "Stack:", cB.Stack())
+		}
+		if *delay > 0 {
+			time.Sleep(*delay)
+		}
+		if err := limit(steps, start); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			printPlot(watch)
+			os.Exit(exitCode(err))
+		}
+		if done, err := cB.Swim(); err != nil {
+			cB.PrintFailure(err.(*fish.Failure))
+			printPlot(watch)
+			os.Exit(cli.ExitError)
+		} else if done {
+			printPlot(watch)
+			return
 		}
-		time.Sleep(*delay)
 	}
 }
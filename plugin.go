@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"github.com/redstarcoder/go-fish/fish"
+	"github.com/redstarcoder/go-fish/internal/cli"
+	"io"
+	"os"
+	"plugin"
+	"strings"
+)
+
+// pluginList is the -plugin flag, which may be given more than once.
+type pluginList []string
+
+func (p *pluginList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pluginList) Set(s string) error {
+	*p = append(*p, s)
+	return nil
+}
+
+// capabilities is the -allow flag.
+type capabilities []fish.Capability
+
+func (c *capabilities) String() string {
+	s := make([]string, len(*c))
+	for i, v := range *c {
+		s[i] = string(v)
+	}
+	return strings.Join(s, ",")
+}
+
+func (c *capabilities) Set(s string) error {
+	cs, err := fish.ParseCapabilities(s)
+	*c = append(*c, cs...)
+	return err
+}
+
+// loadPlugin loads the extension described by spec. A path ending in ".so" is a Go plugin exporting a
+// variable named Extension which implements fish.Extension and fish.Declarer. Anything else is a command, with its
+// arguments separated by spaces, which is started as a fish.Process.
+func loadPlugin(spec string) (fish.Extension, error) {
+	if !strings.HasSuffix(spec, ".so") {
+		args := strings.Fields(spec)
+		if len(args) == 0 {
+			return nil, fmt.Errorf("empty plugin command")
+		}
+		return fish.StartProcess(args[0], args[1:]...)
+	}
+	p, err := plugin.Open(spec)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup("Extension")
+	if err != nil {
+		return nil, err
+	}
+	switch ext := sym.(type) {
+	case *fish.Extension:
+		return *ext, nil
+	case fish.Extension:
+		return ext, nil
+	}
+	return nil, fmt.Errorf("%s: Extension is a %T, which doesn't implement fish.Extension", spec, sym)
+}
+
+// loadPlugins loads every -plugin, exiting if one can't be loaded or needs a capability not given by -allow.
+// Each plugin's manifest is only checked once it's loaded, after its code has started running, so -allow
+// limits what a plugin may declare rather than what it may do. A denied process is closed before exiting.
+func loadPlugins() {
+	for _, spec := range plugins {
+		ext, err := loadPlugin(spec)
+		if err == nil {
+			if _, err = fish.Approve(ext, allowed...); err != nil {
+				if c, ok := ext.(io.Closer); ok {
+					c.Close()
+				}
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "plugin %s: %v\n", spec, err)
+			os.Exit(cli.ExitUsage)
+		}
+		extensions = append(extensions, ext)
+	}
+}
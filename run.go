@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redstarcoder/go-fish/fish"
+	"github.com/redstarcoder/go-fish/internal/cli"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	errStepLimit     = fish.ErrMaxSteps
+	errTimeout       = errors.New("timeout exceeded")
+	errInvalidScript = errors.New("invalid script")
+	errInvalidArg    = errors.New("invalid argument") // A file or directory named by a flag or argument
+)
+
+// exitCode returns the exit code for a fish which stopped with err.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return cli.ExitOK
+	case errors.Is(err, errInvalidArg):
+		return cli.ExitUsage
+	case errors.Is(err, errInvalidScript):
+		return cli.ExitInvalid
+	case errors.Is(err, errStepLimit):
+		return cli.ExitStepLimit
+	case errors.Is(err, errTimeout):
+		return cli.ExitTimeout
+	}
+	return cli.ExitError
+}
+
+// limit returns an error if a fish which has taken steps ticks since start should be stopped by -steps or
+// -timeout.
+func limit(steps int, start time.Time) error {
+	if *maxsteps > 0 && steps >= *maxsteps {
+		return errStepLimit
+	}
+	if *timeout > 0 && time.Since(start) > *timeout {
+		return errTimeout
+	}
+	return nil
+}
+
+// result is the outcome of running a script with execute.
+type result struct {
+	Output     []byte
+	Stack      []float64
+	Steps      int
+	Duration   time.Duration
+	Err        error
+	Changed    *fish.Divergence // How the fish differed from its recorded trace, with -compare
+	OutputDiff string           // How the fish's output differed from its recorded output, with -compare
+	TraceErr   error            // Why its trace couldn't be recorded or compared, with -record or -compare
+}
+
+// options returns the options shared by every way of running a script.
+func options() []fish.Option {
+	var opts []fish.Option
+	if *inputfile != "" {
+		opts = append(opts, fish.WithInput(bytes.NewReader(loadInput(*inputfile))))
+	}
+	if *runes {
+		opts = append(opts, fish.WithRunes())
+	}
+	if *seed != 0 {
+		opts = append(opts, fish.WithRand(rand.NewSource(*seed)))
+	}
+	if *debugop {
+		opts = append(opts, fish.WithExtension(fish.DebugExtension{}))
+	}
+	if *asserts {
+		opts = append(opts, fish.WithExtension(fish.AssertExtension{}))
+	}
+	if *calls {
+		opts = append(opts, fish.WithExtension(fish.CallExtension{}))
+	}
+	if *timing {
+		opts = append(opts, fish.WithExtension(fish.Timing{}))
+	}
+	if *numinput {
+		opts = append(opts, fish.WithExtension(fish.NumberInput{}))
+	}
+	for _, ext := range extensions {
+		opts = append(opts, fish.WithExtension(ext))
+	}
+	return opts
+}
+
+// execute runs script to completion, or until it's stopped by -steps or -timeout, capturing its output
+// instead of writing it.
+func execute(script string, opts []fish.Option) (res result) {
+	var log fish.EventLog
+	defer func() {
+		if r := recover(); r != nil {
+			res.Err = fmt.Errorf("%w: %v", errInvalidScript, r)
+		}
+	}()
+	cB := fish.NewCodeBox(script, append([]float64{}, initialstack.s...), mode,
+		append(opts, fish.WithOutput(ioutil.Discard), fish.WithEventLog(&log))...)
+	defer cB.Close()
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	var err error
+	if res.Steps, err = cB.RunContext(ctx, *maxsteps); err == context.DeadlineExceeded {
+		err = errTimeout
+	}
+	res.Duration = time.Since(start)
+	res.Output = []byte(log.Output())
+	res.Stack = append([]float64{}, cB.Stack()...)
+	res.Err = err
+	return
+}
+
+// runFile runs the script at path, which is under dir. With -record, it writes a trace of the run and its
+// output into the -record directory, and with -compare, it compares the run with the trace and output in the
+// -compare directory.
+func runFile(dir, path string) (res result) {
+	pp, err := fish.Preprocess(path)
+	if err != nil {
+		res.Err = fmt.Errorf("%w: %v", errInvalidScript, err)
+		return
+	}
+	opts := append(options(), fish.WithSourceMap(pp.Map))
+	if *recorddir == "" && *comparedir == "" {
+		return execute(pp.Script, opts)
+	}
+	buf := new(bytes.Buffer)
+	tw := fish.NewTraceWriter(buf, 0, true)
+	res = execute(pp.Script, append(opts, fish.WithTrace(tw)))
+	if res.TraceErr = tw.Close(); res.TraceErr != nil {
+		return
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		panic(err)
+	}
+	if *recorddir != "" {
+		name := filepath.Join(*recorddir, rel+".trace")
+		if res.TraceErr = os.MkdirAll(filepath.Dir(name), 0755); res.TraceErr == nil {
+			res.TraceErr = ioutil.WriteFile(name, buf.Bytes(), 0644)
+		}
+		if res.TraceErr == nil {
+			res.TraceErr = ioutil.WriteFile(filepath.Join(*recorddir, rel+".out"), res.Output, 0644)
+		}
+	}
+	if *comparedir != "" && res.TraceErr == nil {
+		res.Changed, res.TraceErr = compare(filepath.Join(*comparedir, rel+".trace"), buf.Bytes())
+	}
+	if *comparedir != "" && res.TraceErr == nil {
+		res.OutputDiff, res.TraceErr = compareOutput(filepath.Join(*comparedir, rel+".out"), res.Output)
+	}
+	return
+}
+
+// compareOutput compares output with the output recorded in the file name, and describes where it first
+// differs, or returns "" if they're the same.
+func compareOutput(name string, output []byte) (string, error) {
+	recorded, err := ioutil.ReadFile(name)
+	if err != nil || bytes.Equal(recorded, output) {
+		return "", err
+	}
+	i := 0
+	for i < len(recorded) && i < len(output) && recorded[i] == output[i] {
+		i++
+	}
+	return fmt.Sprintf("output at byte %d: %q, not %q", i, excerpt(output[i:]), excerpt(recorded[i:])), nil
+}
+
+// excerpt returns the start of b, short enough for a summary.
+func excerpt(b []byte) []byte {
+	if len(b) > 16 {
+		return b[:16]
+	}
+	return b
+}
+
+// compare compares the trace in the file name with trace.
+func compare(name string, trace []byte) (*fish.Divergence, error) {
+	recorded, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	old, err := fish.OpenTrace(bytes.NewReader(recorded), int64(len(recorded)))
+	if err != nil {
+		return nil, err
+	}
+	tr, err := fish.OpenTrace(bytes.NewReader(trace), int64(len(trace)))
+	if err != nil {
+		return nil, err
+	}
+	return old.Compare(tr)
+}
+
+// scriptPaths returns the paths of the scripts in dir and its subdirectories, in order.
+func scriptPaths(dir string) []string {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() && strings.HasSuffix(path, ".fish") {
+			paths = append(paths, path)
+		}
+		return err
+	})
+	if err != nil {
+		stop(fmt.Errorf("%w: %v", errInvalidArg, err))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// batch runs every .fish file under dir, -parallel at a time, and prints each one's output followed by a
+// summary. It returns the exit code of the first one which failed, or cli.ExitOK.
+func batch(dir string) int {
+	paths := scriptPaths(dir)
+	results := make([]result, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < *parallel || i == 0; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = runFile(dir, paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	code := cli.ExitOK
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(w, "Program\tSteps\tTime\tResult")
+	for i, res := range results {
+		fmt.Printf("== %s ==\n%s\n", paths[i], res.Output)
+		status, c := "ok", cli.ExitOK
+		switch {
+		case res.Err != nil:
+			status, c = res.Err.Error(), exitCode(res.Err)
+		case res.TraceErr != nil:
+			status, c = "trace: "+res.TraceErr.Error(), cli.ExitChanged
+		case res.Changed != nil:
+			status, c = "changed at "+res.Changed.String(), cli.ExitChanged
+		case res.OutputDiff != "":
+			status, c = "changed "+res.OutputDiff, cli.ExitChanged
+		}
+		if code == cli.ExitOK {
+			code = c
+		}
+		fmt.Fprintf(w, "%s\t%d\t%v\t%s\n", paths[i], res.Steps, res.Duration, status)
+	}
+	fmt.Println()
+	w.Flush()
+	return code
+}
+
+// soakTest runs scripts -soak times over, reporting their resource usage, and stops with an error if
+// resources accumulate.
+func soakTest(scripts []string) {
+	steps := *maxsteps
+	if steps <= 0 {
+		steps = soakSteps
+	}
+	report, err := fish.Soak(scripts, *soak, steps, options()...)
+	if report == nil {
+		stop(fmt.Errorf("%w: %v", errInvalidScript, err))
+	}
+	fmt.Println(report)
+	if err != nil {
+		stop(err)
+	}
+}